@@ -3,6 +3,8 @@ package hub
 import (
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
 
 	"github.com/stolostron/maestro-addon/pkg/hub"
 	"github.com/stolostron/maestro-addon/pkg/version"
@@ -21,5 +23,31 @@ func NewHubManager() *cobra.Command {
 	o.AddFlags(flags)
 	flags.BoolVar(&cmdConfig.DisableLeaderElection, "disable-leader-election", false, "Disable leader election for the manager.")
 
+	var printPrincipalCluster string
+	flags.StringVar(&printPrincipalCluster, "print-principal", "",
+		"Print the Kafka principal of the agent of the given cluster and exit, it supports the kafka and strimzi message queues.")
+
+	run := cmd.Run
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		if len(printPrincipalCluster) == 0 {
+			run(cmd, args)
+			return
+		}
+
+		// the broker config of a secret is read with the --kubeconfig, or the in-cluster config if it is not set
+		kubeConfigFile, err := cmd.Flags().GetString("kubeconfig")
+		if err != nil {
+			klog.Fatal(err)
+		}
+		kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfigFile)
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		if err := o.PrintPrincipal(cmd.Context(), kubeConfig, cmd.OutOrStdout(), printPrincipalCluster); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
 	return cmd
 }
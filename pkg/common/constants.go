@@ -5,4 +5,23 @@ const (
 	AddOnTemplateName           = "maestro-addon-template"
 	MessageQueueCertsSecretName = "maestro-mq-certs" // #nosec G101
 	MessageQueueCAKey           = "ca.crt"
+
+	// MessageQueueScramConfigName is the AddOnDeploymentConfig in the cluster namespace that stores the SCRAM credential
+	// of the agent in its customized variables, the addon framework renders the variables to the agent config.
+	MessageQueueScramConfigName        = "maestro-addon-kafka-scram" // #nosec G101
+	MessageQueueScramUserVariable      = "KAFKA_SCRAM_USERNAME"
+	MessageQueueScramPasswordVariable  = "KAFKA_SCRAM_PASSWORD" // #nosec G101
+	MessageQueueScramMechanismVariable = "KAFKA_SCRAM_MECHANISM"
+
+	// ManagedClusterCleanupFinalizer is added to the ManagedCluster by the maestro-addon manager to
+	// make sure the maestro consumer and message queue authorizations of the cluster are cleaned up
+	// before the cluster is deleted.
+	ManagedClusterCleanupFinalizer = "maestro-addon.open-cluster-management.io/cleanup"
+
+	// KafkaClientQuotasAnnotation overrides the default Kafka client quotas of the agent of a ManagedCluster, the value
+	// is a comma-separated list of key=value pairs, e.g. producerByteRate=1048576,requestPercentage=50.
+	KafkaClientQuotasAnnotation = "maestro-addon.open-cluster-management.io/kafka-client-quotas"
+
+	// ClusterNameLabel is added to the message queue authorization resources that are created for a cluster.
+	ClusterNameLabel = "maestro-addon.open-cluster-management.io/cluster"
 )
@@ -3,14 +3,77 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"text/template"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"github.com/stolostron/maestro-addon/pkg/common"
 )
 
+// KafkaACLProfile determines the ACLs that are granted to an agent.
+type KafkaACLProfile string
+
+const (
+	// KafkaACLProfileRestricted grants an agent the least privileges: read on the source events topic, write
+	// on the agent events topic and read on its own consumer groups (prefixed with the cluster name).
+	KafkaACLProfileRestricted KafkaACLProfile = "restricted"
+
+	// KafkaACLProfilePermissive grants an agent all operations on the event topics and all consumer groups,
+	// this is the legacy profile and only is kept for migrating existing clusters.
+	KafkaACLProfilePermissive KafkaACLProfile = "permissive"
+)
+
+// KafkaAgentAuthentication determines how the agents authenticate to the Kafka broker.
+type KafkaAgentAuthentication string
+
+const (
+	// KafkaAgentAuthenticationCertificate authenticates the agents with the certificates that are signed by the
+	// maestro-addon custom signer, the ACLs are bound to the certificate principals.
+	KafkaAgentAuthenticationCertificate KafkaAgentAuthentication = "certificate"
+
+	// KafkaAgentAuthenticationScram authenticates the agents with the SCRAM users that are created for each
+	// cluster, the ACLs are bound to the SCRAM user principals.
+	KafkaAgentAuthenticationScram KafkaAgentAuthentication = "scram"
+)
+
+// KafkaACLOptions are the options to create the ACLs of an agent.
+type KafkaACLOptions struct {
+	// Profile determines the ACLs that are granted to the agent.
+	Profile KafkaACLProfile
+
+	// AgentAuthentication determines the principal that the ACLs of the agent are bound to.
+	AgentAuthentication KafkaAgentAuthentication
+
+	// RemoveStaleACLs removes the ACLs of the agent principal that are not in the expected ACLs of the profile,
+	// so the manual changes of the agent ACLs on the broker are corrected.
+	RemoveStaleACLs bool
+
+	// PrincipalTemplate renders the principal of the agent certificate, the default dn template is used if it is nil.
+	PrincipalTemplate *template.Template
+}
+
+// KafkaTopicSpecs are the specifications of the source events topic and agent events topic, the zero partitions and
+// replication factor are set with defaults.
+type KafkaTopicSpecs struct {
+	SourceEvents kafka.TopicSpecification
+	AgentEvents  kafka.TopicSpecification
+}
+
+const (
+	// sourceEventsTopic is a topic for sources to publish their events.
+	sourceEventsTopic = "sourceevents"
+	// agentEventsTopic is a topic for agents to publish their events.
+	agentEventsTopic = "agentevents"
+
+	// the topics are shared by all clusters
+	sharedTopicPartitions = 50
+
+	defaultTopicReplicationFactor = 1
+)
+
 // an interface for kafka.AdminClient, this will help with testing
 type KafkaAdminClient interface {
 	DescribeTopics(ctx context.Context, topics kafka.TopicCollection,
@@ -21,169 +84,425 @@ type KafkaAdminClient interface {
 		options ...kafka.CreateTopicsAdminOption) (result []kafka.TopicResult, err error)
 	CreateACLs(ctx context.Context, aclBindings kafka.ACLBindings,
 		options ...kafka.CreateACLsAdminOption) (result []kafka.CreateACLResult, err error)
+	DeleteACLs(ctx context.Context, aclBindingFilters kafka.ACLBindingFilters,
+		options ...kafka.DeleteACLsAdminOption) (result []kafka.DeleteACLsResult, err error)
+	CreatePartitions(ctx context.Context, partitions []kafka.PartitionsSpecification,
+		options ...kafka.CreatePartitionsAdminOption) (result []kafka.TopicResult, err error)
+	DescribeUserScramCredentials(ctx context.Context, users []string,
+		options ...kafka.DescribeUserScramCredentialsAdminOption) (result kafka.DescribeUserScramCredentialsResult, err error)
+	AlterUserScramCredentials(ctx context.Context, upsertions []kafka.UserScramCredentialUpsertion,
+		deletions []kafka.UserScramCredentialDeletion,
+		options ...kafka.AlterUserScramCredentialsAdminOption) (result kafka.AlterUserScramCredentialsResult, err error)
+	DescribeConsumerGroups(ctx context.Context, groups []string,
+		options ...kafka.DescribeConsumerGroupsAdminOption) (result kafka.DescribeConsumerGroupsResult, err error)
+	ListConsumerGroupOffsets(ctx context.Context, groupsPartitions []kafka.ConsumerGroupTopicPartitions,
+		options ...kafka.ListConsumerGroupOffsetsAdminOption) (result kafka.ListConsumerGroupOffsetsResult, err error)
+	ListOffsets(ctx context.Context, topicPartitionOffsets map[kafka.TopicPartition]kafka.OffsetSpec,
+		options ...kafka.ListOffsetsAdminOption) (result kafka.ListOffsetsResult, err error)
+	DescribeCluster(ctx context.Context,
+		options ...kafka.DescribeClusterAdminOption) (result kafka.DescribeClusterResult, err error)
+	// AlterClientQuotas sets the client quotas of the given user entity, the quotas with nil values are removed.
+	AlterClientQuotas(ctx context.Context, user string, quotas map[string]*float64) error
+	Close()
 }
 
-// CreteKafkaTopics creates placeholder topics.
-func CreteKafkaTopics(ctx context.Context, config *kafka.ConfigMap, sourceID string) error {
-	client, err := kafka.NewAdminClient(config)
-	if err != nil {
-		return err
+// CreteKafkaTopics creates placeholder topics
+func CreteKafkaTopics(ctx context.Context, adminClient KafkaAdminClient, specs KafkaTopicSpecs) error {
+	return createKafkaTopics(ctx, adminClient, toKafkaTopicSpecifications(specs)...)
+}
+
+func CreateACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions, clusterName string) error {
+	return createKafkaACLs(ctx, adminClient, opts, clusterName, sourceEventsTopic, agentEventsTopic)
+}
+
+func DeleteACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions, clusterName string) error {
+	return deleteKafkaACLs(ctx, adminClient, opts, clusterName, sourceEventsTopic, agentEventsTopic)
+}
+
+// CreateBulkACLs creates the ACLs of the agents of the given clusters in one pass, the existing ACLs are fetched with
+// one DescribeACLs call for each agent principal and the missing ACLs of all clusters are created with one CreateACLs
+// call, this reduces the round trips when a lot of clusters are onboarded.
+func CreateBulkACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions, clusterNames []string) error {
+	expectedACLBindings := map[string][]kafka.ACLBinding{}
+	for _, clusterName := range clusterNames {
+		aclBindings, err := KafkaACLBindings(opts, clusterName)
+		if err != nil {
+			return err
+		}
+
+		for _, acl := range aclBindings {
+			expectedACLBindings[acl.Principal] = append(expectedACLBindings[acl.Principal], acl)
+		}
 	}
-	defer client.Close()
 
-	return createKafkaTopics(ctx, client, kafkaTopics()...)
+	return ensurePrincipalsKafkaACLs(ctx, adminClient, expectedACLBindings, opts.RemoveStaleACLs)
 }
 
-func CreateACLs(ctx context.Context, config *kafka.ConfigMap, sourceID, clusterName string) error {
-	adminClient, err := kafka.NewAdminClient(config)
+// KafkaACLBindings returns the expected ACLs of the agent of the given cluster, they are used by the creators that
+// manage the ACLs out of the Kafka admin API.
+func KafkaACLBindings(opts KafkaACLOptions, clusterName string) ([]kafka.ACLBinding, error) {
+	principal, err := ToAgentPrincipal(opts, clusterName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer adminClient.Close()
 
-	return createKafkaACLs(ctx, adminClient, clusterName, kafkaTopics()...)
+	return kafkaACLBindings(opts.Profile, principal, ToAgentGroupID(clusterName), sourceEventsTopic, agentEventsTopic)
 }
 
-func createKafkaTopics(ctx context.Context, adminClient KafkaAdminClient, newTopics ...string) error {
+// createKafkaTopics creates the topics with the given specifications, if a topic already exists and its partitions
+// are less than the specified partitions, the partitions of the topic will be expanded.
+func createKafkaTopics(ctx context.Context, adminClient KafkaAdminClient, specs ...kafka.TopicSpecification) error {
 	logger := klog.FromContext(ctx)
 
+	newTopics := []string{}
+	for _, spec := range specs {
+		newTopics = append(newTopics, spec.Topic)
+	}
+
 	topics, err := adminClient.DescribeTopics(ctx, kafka.NewTopicCollectionOfTopicNames(newTopics))
 	if err != nil {
 		return err
 	}
 
 	topicSpecs := []kafka.TopicSpecification{}
-	for _, topic := range newTopics {
-		if hasKafkaTopic(topics.TopicDescriptions, topic) {
-			logger.V(4).Info(fmt.Sprintf("topic %s already exists", topic))
+	partitionSpecs := []kafka.PartitionsSpecification{}
+	for _, spec := range specs {
+		topic := findKafkaTopic(topics.TopicDescriptions, spec.Topic)
+		if topic == nil {
+			topicSpecs = append(topicSpecs, spec)
 			continue
 		}
 
-		topicSpecs = append(topicSpecs, kafka.TopicSpecification{
-			Topic:             topic,
-			NumPartitions:     50,
-			ReplicationFactor: 1,
-		})
-	}
+		if len(topic.Partitions) < spec.NumPartitions {
+			partitionSpecs = append(partitionSpecs, kafka.PartitionsSpecification{
+				Topic:      spec.Topic,
+				IncreaseTo: spec.NumPartitions,
+			})
+			continue
+		}
 
-	if len(topicSpecs) == 0 {
-		return nil
+		logger.V(4).Info(fmt.Sprintf("topic %s already exists", spec.Topic))
 	}
 
-	results, err := adminClient.CreateTopics(ctx, topicSpecs)
-	if err != nil {
-		return err
+	errs := []error{}
+	if len(topicSpecs) != 0 {
+		results, err := adminClient.CreateTopics(ctx, topicSpecs)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range results {
+			if r.Error.Code() == kafka.ErrNoError {
+				logger.V(4).Info(fmt.Sprintf("topic %s created successfully", r.Topic))
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("failed to create topic %s, %s", r.Topic, r.Error.String()))
+		}
 	}
 
-	errs := []error{}
-	for _, r := range results {
-		if r.Error.Code() == kafka.ErrNoError {
-			logger.V(4).Info(fmt.Sprintf("topic %s created successfully", r.Topic))
-			continue
+	if len(partitionSpecs) != 0 {
+		results, err := adminClient.CreatePartitions(ctx, partitionSpecs)
+		if err != nil {
+			return err
 		}
 
-		errs = append(errs, fmt.Errorf("failed to create topic %s, %s", r.Topic, r.Error.String()))
+		for _, r := range results {
+			if r.Error.Code() == kafka.ErrNoError {
+				logger.Info(fmt.Sprintf("partitions of topic %s expanded successfully", r.Topic))
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("failed to expand partitions of topic %s, %s", r.Topic, r.Error.String()))
+		}
 	}
 
 	return errors.NewAggregate(errs)
 }
 
-// Using two topics to pub/sub events among the Kafka broker and agents
-// TODO consider how to control the agent ACLs
-func createKafkaACLs(ctx context.Context, adminClient KafkaAdminClient, clusterName string, topics ...string) error {
+// Using two topics to pub/sub events among the Kafka broker and agents, the ACLs of the agent on the
+// topics are determined by the given profile.
+func createKafkaACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions,
+	clusterName, sourceTopic, agentTopic string) error {
+	principal, err := ToAgentPrincipal(opts, clusterName)
+	if err != nil {
+		return err
+	}
+
+	expectedACLBindings, err := kafkaACLBindings(opts.Profile, principal, ToAgentGroupID(clusterName), sourceTopic, agentTopic)
+	if err != nil {
+		return err
+	}
+
+	return ensureKafkaACLs(ctx, adminClient, principal, expectedACLBindings, opts.RemoveStaleACLs)
+}
+
+// ensureKafkaACLs creates the expected ACLs of the given principal that do not exist, the other ACLs of the principal
+// are removed if removeStaleACLs is true.
+func ensureKafkaACLs(ctx context.Context, adminClient KafkaAdminClient, principal string,
+	expectedACLBindings []kafka.ACLBinding, removeStaleACLs bool) error {
+	return ensurePrincipalsKafkaACLs(ctx, adminClient, map[string][]kafka.ACLBinding{principal: expectedACLBindings},
+		removeStaleACLs)
+}
+
+// ensurePrincipalsKafkaACLs fetches the existing ACLs of each principal with one DescribeACLs call and diffs them with
+// the expected ACLs locally, then the missing ACLs of all principals are created with one CreateACLs call and the
+// stale ACLs of all principals are removed with one DeleteACLs call if removeStaleACLs is true.
+func ensurePrincipalsKafkaACLs(ctx context.Context, adminClient KafkaAdminClient,
+	expectedACLBindings map[string][]kafka.ACLBinding, removeStaleACLs bool) error {
 	logger := klog.FromContext(ctx)
 
-	principal := toKafkaPrincipal(clusterName)
+	principals := sets.List(sets.KeySet(expectedACLBindings))
 
-	expectedACLBindings := []kafka.ACLBinding{{
-		Type:                kafka.ResourceGroup,
-		Name:                "*",
-		ResourcePatternType: kafka.ResourcePatternTypeLiteral,
-		Principal:           principal,
-		Host:                "*",
-		Operation:           kafka.ACLOperationAll,
-		PermissionType:      kafka.ACLPermissionTypeAllow,
-	}}
-
-	for _, topic := range topics {
-		expectedACLBindings = append(expectedACLBindings, kafka.ACLBinding{
-			Type:                kafka.ResourceTopic,
-			Name:                topic,
-			ResourcePatternType: kafka.ResourcePatternTypeLiteral,
-			Principal:           principal,
-			Host:                "*",
-			Operation:           kafka.ACLOperationAll,
-			PermissionType:      kafka.ACLPermissionTypeAllow,
-		})
-	}
-
-	aclBindings := []kafka.ACLBinding{}
-	for _, acl := range expectedACLBindings {
-		result, err := adminClient.DescribeACLs(ctx, acl)
+	missingACLBindings := kafka.ACLBindings{}
+	staleACLFilters := kafka.ACLBindingFilters{}
+	for _, principal := range principals {
+		result, err := adminClient.DescribeACLs(ctx, toKafkaPrincipalFilter(principal))
 		if err != nil {
 			return err
 		}
+		if result.Error.Code() != kafka.ErrNoError {
+			return fmt.Errorf("failed to describe acls for %s, %s", principal, result.Error.String())
+		}
 
-		if hasKafkaACL(result, acl) {
-			logger.V(4).Info(fmt.Sprintf("acl %s/%s already exists for %s", acl.Type, acl.Name, acl.Principal))
+		for _, acl := range expectedACLBindings[principal] {
+			if containsKafkaACL(result.ACLBindings, acl) {
+				logger.V(4).Info(fmt.Sprintf("acl %s/%s (%s) already exists for %s", acl.Type, acl.Name, acl.Operation, acl.Principal))
+				continue
+			}
+
+			missingACLBindings = append(missingACLBindings, acl)
+		}
+
+		if !removeStaleACLs {
 			continue
 		}
 
-		aclBindings = append(aclBindings, acl)
+		for _, acl := range result.ACLBindings {
+			if !containsKafkaACL(expectedACLBindings[principal], acl) {
+				staleACLFilters = append(staleACLFilters, acl)
+			}
+		}
 	}
 
-	if len(aclBindings) == 0 {
+	if len(missingACLBindings) != 0 {
+		results, err := adminClient.CreateACLs(ctx, missingACLBindings)
+		if err != nil {
+			return err
+		}
+
+		errs := []error{}
+		for _, r := range results {
+			if r.Error.Code() != kafka.ErrNoError {
+				errs = append(errs, fmt.Errorf("failed to create acl %s", r.Error.String()))
+			}
+		}
+		if len(errs) != 0 {
+			return errors.NewAggregate(errs)
+		}
+
+		logger.V(4).Info(fmt.Sprintf("%d acls is created successfully for %d principals", len(missingACLBindings), len(principals)))
+	}
+
+	return deleteStaleKafkaACLs(ctx, adminClient, staleACLFilters)
+}
+
+// deleteStaleKafkaACLs deletes the given stale ACLs.
+func deleteStaleKafkaACLs(ctx context.Context, adminClient KafkaAdminClient, staleACLFilters kafka.ACLBindingFilters) error {
+	logger := klog.FromContext(ctx)
+
+	if len(staleACLFilters) == 0 {
 		return nil
 	}
 
-	results, err := adminClient.CreateACLs(ctx, aclBindings)
+	results, err := adminClient.DeleteACLs(ctx, staleACLFilters)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for i, r := range results {
+		if r.Error.Code() != kafka.ErrNoError {
+			errs = append(errs, fmt.Errorf("failed to delete stale acl %s", r.Error.String()))
+			continue
+		}
+
+		if i < len(staleACLFilters) {
+			acl := staleACLFilters[i]
+			logger.V(2).Info(fmt.Sprintf("stale acl %s/%s (%s %s) is deleted for %s",
+				acl.Type, acl.Name, acl.PermissionType, acl.Operation, acl.Principal))
+		}
+	}
+
+	return errors.NewAggregate(errs)
+}
+
+// deleteKafkaACLs deletes the ACLs that are created by createKafkaACLs for the given cluster, the ACLs of
+// all profiles are deleted, so the ACLs of a cluster that is migrated from another profile are also deleted.
+func deleteKafkaACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions,
+	clusterName, sourceTopic, agentTopic string) error {
+	logger := klog.FromContext(ctx)
+
+	principal, err := ToAgentPrincipal(opts, clusterName)
+	if err != nil {
+		return err
+	}
+
+	// each binding is used as an exact filter, so only the bindings created for the cluster are deleted
+	filters := kafka.ACLBindingFilters{}
+	for _, profile := range []KafkaACLProfile{KafkaACLProfileRestricted, KafkaACLProfilePermissive} {
+		aclBindings, err := kafkaACLBindings(profile, principal, ToAgentGroupID(clusterName), sourceTopic, agentTopic)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, aclBindings...)
+	}
+
+	results, err := adminClient.DeleteACLs(ctx, filters)
 	if err != nil {
 		return err
 	}
 
+	if len(results) != len(filters) {
+		return fmt.Errorf("expected %d acl deletion results, but got %d", len(filters), len(results))
+	}
+
 	errs := []error{}
-	for _, r := range results {
+	for i, r := range results {
+		filter := filters[i]
 		if r.Error.Code() != kafka.ErrNoError {
-			errs = append(errs, fmt.Errorf("failed to create acl %s", r.Error.String()))
+			errs = append(errs, fmt.Errorf("failed to delete acl %s/%s for %s, %s",
+				filter.Type, filter.Name, filter.Principal, r.Error.String()))
+			continue
+		}
+
+		if len(r.ACLBindings) == 0 {
+			logger.V(2).Info(fmt.Sprintf("acl %s/%s is already absent for %s", filter.Type, filter.Name, filter.Principal))
+			continue
+		}
+
+		for _, acl := range r.ACLBindings {
+			logger.V(2).Info(fmt.Sprintf("acl %s/%s (%s) is deleted for %s", acl.Type, acl.Name, acl.Operation, acl.Principal))
 		}
 	}
 	if len(errs) == 0 {
-		logger.V(4).Info(fmt.Sprintf("acls is created successfully for agent %s", principal))
+		logger.V(4).Info(fmt.Sprintf("acls is deleted successfully for agent %s", principal))
 	}
 
 	return errors.NewAggregate(errs)
 }
 
-func hasKafkaTopic(topics []kafka.TopicDescription, topic string) bool {
-	for _, t := range topics {
-		if t.Error.Code() == kafka.ErrNoError && t.Name == topic {
-			return true
+// kafkaACLBindings returns the ACLs of the given agent principal with the given profile, the restricted profile only
+// grants the given consumer group of the agent.
+func kafkaACLBindings(profile KafkaACLProfile, principal, groupID, sourceTopic, agentTopic string) ([]kafka.ACLBinding, error) {
+	switch profile {
+	case KafkaACLProfileRestricted:
+		return []kafka.ACLBinding{
+			// the agent subscribes the source events
+			newKafkaACLBinding(principal, kafka.ResourceTopic, sourceTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationRead),
+			newKafkaACLBinding(principal, kafka.ResourceTopic, sourceTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationDescribe),
+			// the agent publishes the agent events
+			newKafkaACLBinding(principal, kafka.ResourceTopic, agentTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationWrite),
+			newKafkaACLBinding(principal, kafka.ResourceTopic, agentTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationDescribe),
+			// the agent only can join the consumer group of its own cluster, <clusterName>-work-agent. The group is
+			// literal, a prefix <clusterName>- would also match the groups of the clusters whose names start with it
+			newKafkaACLBinding(principal, kafka.ResourceGroup, groupID, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationRead),
+			newKafkaACLBinding(principal, kafka.ResourceGroup, groupID, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationDescribe),
+		}, nil
+	case KafkaACLProfilePermissive:
+		return []kafka.ACLBinding{
+			newKafkaACLBinding(principal, kafka.ResourceGroup, "*", kafka.ResourcePatternTypeLiteral, kafka.ACLOperationAll),
+			newKafkaACLBinding(principal, kafka.ResourceTopic, sourceTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationAll),
+			newKafkaACLBinding(principal, kafka.ResourceTopic, agentTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationAll),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kafka acl profile %q", profile)
+	}
+}
+
+func newKafkaACLBinding(principal string, resourceType kafka.ResourceType, name string,
+	patternType kafka.ResourcePatternType, operation kafka.ACLOperation) kafka.ACLBinding {
+	return kafka.ACLBinding{
+		Type:                resourceType,
+		Name:                name,
+		ResourcePatternType: patternType,
+		Principal:           principal,
+		Host:                "*",
+		Operation:           operation,
+		PermissionType:      kafka.ACLPermissionTypeAllow,
+	}
+}
+
+func findKafkaTopic(topics []kafka.TopicDescription, topic string) *kafka.TopicDescription {
+	for i := range topics {
+		if topics[i].Error.Code() == kafka.ErrNoError && topics[i].Name == topic {
+			return &topics[i]
 		}
 	}
 
-	return false
+	return nil
 }
 
-func hasKafkaACL(acls *kafka.DescribeACLsResult, binding kafka.ACLBinding) bool {
-	if acls.Error.Code() == kafka.ErrNoError {
-		for _, a := range acls.ACLBindings {
-			if a.Name == binding.Name {
-				return true
-			}
+// containsKafkaACL compares the full bindings, so a binding that has the same resource but a different
+// principal, operation, permission type or pattern type does not mask the expected binding.
+func containsKafkaACL(acls []kafka.ACLBinding, binding kafka.ACLBinding) bool {
+	for _, a := range acls {
+		if a.Type == binding.Type &&
+			a.Name == binding.Name &&
+			a.ResourcePatternType == binding.ResourcePatternType &&
+			a.Principal == binding.Principal &&
+			a.Host == binding.Host &&
+			a.Operation == binding.Operation &&
+			a.PermissionType == binding.PermissionType {
+			return true
 		}
 	}
 	return false
 }
 
-func kafkaTopics() []string {
-	return []string{"sourceevents", "agentevents"}
+// toKafkaPrincipalFilter returns a filter that matches all ACLs of the given principal.
+func toKafkaPrincipalFilter(principal string) kafka.ACLBindingFilter {
+	return kafka.ACLBindingFilter{
+		Type:                kafka.ResourceAny,
+		ResourcePatternType: kafka.ResourcePatternTypeAny,
+		Principal:           principal,
+		Operation:           kafka.ACLOperationAny,
+		PermissionType:      kafka.ACLPermissionTypeAny,
+	}
+}
+
+// toKafkaTopicSpecifications returns the specifications of the topics, the unset partitions and replication factor
+// are set with defaults.
+func toKafkaTopicSpecifications(specs KafkaTopicSpecs) []kafka.TopicSpecification {
+	specs.SourceEvents.Topic = sourceEventsTopic
+	specs.AgentEvents.Topic = agentEventsTopic
+
+	topicSpecs := []kafka.TopicSpecification{}
+	for _, spec := range []kafka.TopicSpecification{specs.SourceEvents, specs.AgentEvents} {
+		if spec.NumPartitions == 0 {
+			spec.NumPartitions = sharedTopicPartitions
+		}
+		if spec.ReplicationFactor == 0 {
+			spec.ReplicationFactor = defaultTopicReplicationFactor
+		}
+		topicSpecs = append(topicSpecs, spec)
+	}
+
+	return topicSpecs
+}
+
+// ToAgentPrincipal returns the principal of the agent of the given cluster with the authentication and principal
+// template of the given options.
+func ToAgentPrincipal(opts KafkaACLOptions, clusterName string) (string, error) {
+	if opts.AgentAuthentication == KafkaAgentAuthenticationScram {
+		return fmt.Sprintf("User:%s", ToKafkaScramUser(clusterName)), nil
+	}
+
+	return ToKafkaPrincipal(opts.PrincipalTemplate, clusterName)
 }
 
-func toKafkaPrincipal(clusterName string) string {
-	commonName := fmt.Sprintf("system:open-cluster-management:cluster:%s:addon:%s:agent:%s-agent",
+// ToAgentCommonName returns the common name of the agent certificate that is signed by the maestro-addon custom signer.
+func ToAgentCommonName(clusterName string) string {
+	return fmt.Sprintf("system:open-cluster-management:cluster:%s:addon:%s:agent:%s-agent",
 		clusterName, common.AddOnName, common.AddOnName)
-	authGroup := "system:authenticated"
-	addOnGroup := fmt.Sprintf("system:open-cluster-management:addon:%s", common.AddOnName)
-	clusterGroup := fmt.Sprintf("system:open-cluster-management:cluster:%s:addon:%s", clusterName, common.AddOnName)
-	return fmt.Sprintf("User:CN=%s,O=%s+O=%s+O=%s", commonName, authGroup, addOnGroup, clusterGroup)
 }
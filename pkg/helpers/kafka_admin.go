@@ -0,0 +1,247 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/klog/v2"
+)
+
+// SharedKafkaAdminClient is a long-lived Kafka admin client that is shared by the reconciles, so the TLS handshake and
+// the metadata fetch are not repeated for each cluster. The librdkafka admin client is safe for concurrent use, so one
+// underlying client serves all the callers. It is created on the first call and recreated after a fatal error, e.g.
+// the credentials are rejected by the broker.
+type SharedKafkaAdminClient struct {
+	sync.RWMutex
+	config    *kafka.ConfigMap
+	newClient func(config *kafka.ConfigMap) (KafkaAdminClient, error)
+	client    KafkaAdminClient
+	closed    bool
+}
+
+var _ KafkaAdminClient = &SharedKafkaAdminClient{}
+
+// NewSharedKafkaAdminClient returns a shared admin client with the given config, the client does not connect to the
+// broker until it is used.
+func NewSharedKafkaAdminClient(config *kafka.ConfigMap) *SharedKafkaAdminClient {
+	return &SharedKafkaAdminClient{
+		config:    config,
+		newClient: NewKafkaAdminClient,
+	}
+}
+
+func (c *SharedKafkaAdminClient) DescribeTopics(ctx context.Context, topics kafka.TopicCollection,
+	options ...kafka.DescribeTopicsAdminOption) (kafka.DescribeTopicsResult, error) {
+	return callKafkaAdmin(ctx, c, "DescribeTopics", func(client KafkaAdminClient) (kafka.DescribeTopicsResult, error) {
+		return client.DescribeTopics(ctx, topics, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) DescribeACLs(ctx context.Context, aclBindingFilter kafka.ACLBindingFilter,
+	options ...kafka.DescribeACLsAdminOption) (*kafka.DescribeACLsResult, error) {
+	return callKafkaAdmin(ctx, c, "DescribeACLs", func(client KafkaAdminClient) (*kafka.DescribeACLsResult, error) {
+		return client.DescribeACLs(ctx, aclBindingFilter, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) CreateTopics(ctx context.Context, topics []kafka.TopicSpecification,
+	options ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error) {
+	return callKafkaAdmin(ctx, c, "CreateTopics", func(client KafkaAdminClient) ([]kafka.TopicResult, error) {
+		return client.CreateTopics(ctx, topics, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) CreateACLs(ctx context.Context, aclBindings kafka.ACLBindings,
+	options ...kafka.CreateACLsAdminOption) ([]kafka.CreateACLResult, error) {
+	return callKafkaAdmin(ctx, c, "CreateACLs", func(client KafkaAdminClient) ([]kafka.CreateACLResult, error) {
+		return client.CreateACLs(ctx, aclBindings, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) DeleteACLs(ctx context.Context, aclBindingFilters kafka.ACLBindingFilters,
+	options ...kafka.DeleteACLsAdminOption) ([]kafka.DeleteACLsResult, error) {
+	return callKafkaAdmin(ctx, c, "DeleteACLs", func(client KafkaAdminClient) ([]kafka.DeleteACLsResult, error) {
+		return client.DeleteACLs(ctx, aclBindingFilters, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) CreatePartitions(ctx context.Context, partitions []kafka.PartitionsSpecification,
+	options ...kafka.CreatePartitionsAdminOption) ([]kafka.TopicResult, error) {
+	return callKafkaAdmin(ctx, c, "CreatePartitions", func(client KafkaAdminClient) ([]kafka.TopicResult, error) {
+		return client.CreatePartitions(ctx, partitions, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) DescribeUserScramCredentials(ctx context.Context, users []string,
+	options ...kafka.DescribeUserScramCredentialsAdminOption) (kafka.DescribeUserScramCredentialsResult, error) {
+	return callKafkaAdmin(ctx, c, "DescribeUserScramCredentials", func(client KafkaAdminClient) (kafka.DescribeUserScramCredentialsResult, error) {
+		return client.DescribeUserScramCredentials(ctx, users, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) DescribeConsumerGroups(ctx context.Context, groups []string,
+	options ...kafka.DescribeConsumerGroupsAdminOption) (kafka.DescribeConsumerGroupsResult, error) {
+	return callKafkaAdmin(ctx, c, "DescribeConsumerGroups", func(client KafkaAdminClient) (kafka.DescribeConsumerGroupsResult, error) {
+		return client.DescribeConsumerGroups(ctx, groups, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) ListConsumerGroupOffsets(ctx context.Context, groupsPartitions []kafka.ConsumerGroupTopicPartitions,
+	options ...kafka.ListConsumerGroupOffsetsAdminOption) (kafka.ListConsumerGroupOffsetsResult, error) {
+	return callKafkaAdmin(ctx, c, "ListConsumerGroupOffsets", func(client KafkaAdminClient) (kafka.ListConsumerGroupOffsetsResult, error) {
+		return client.ListConsumerGroupOffsets(ctx, groupsPartitions, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) ListOffsets(ctx context.Context, topicPartitionOffsets map[kafka.TopicPartition]kafka.OffsetSpec,
+	options ...kafka.ListOffsetsAdminOption) (kafka.ListOffsetsResult, error) {
+	return callKafkaAdmin(ctx, c, "ListOffsets", func(client KafkaAdminClient) (kafka.ListOffsetsResult, error) {
+		return client.ListOffsets(ctx, topicPartitionOffsets, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) DescribeCluster(ctx context.Context,
+	options ...kafka.DescribeClusterAdminOption) (kafka.DescribeClusterResult, error) {
+	return callKafkaAdmin(ctx, c, "DescribeCluster", func(client KafkaAdminClient) (kafka.DescribeClusterResult, error) {
+		return client.DescribeCluster(ctx, options...)
+	})
+}
+
+func (c *SharedKafkaAdminClient) AlterClientQuotas(ctx context.Context, user string, quotas map[string]*float64) error {
+	_, err := callKafkaAdmin(ctx, c, "AlterClientQuotas", func(client KafkaAdminClient) (struct{}, error) {
+		return struct{}{}, client.AlterClientQuotas(ctx, user, quotas)
+	})
+	return err
+}
+
+func (c *SharedKafkaAdminClient) AlterUserScramCredentials(ctx context.Context, upsertions []kafka.UserScramCredentialUpsertion,
+	deletions []kafka.UserScramCredentialDeletion,
+	options ...kafka.AlterUserScramCredentialsAdminOption) (kafka.AlterUserScramCredentialsResult, error) {
+	return callKafkaAdmin(ctx, c, "AlterUserScramCredentials", func(client KafkaAdminClient) (kafka.AlterUserScramCredentialsResult, error) {
+		return client.AlterUserScramCredentials(ctx, upsertions, deletions, options...)
+	})
+}
+
+// SetConfig replaces the config of the shared client and closes the underlying client, so the next call connects to the
+// broker with the new config, e.g. the bootstrap server is changed or the client certificate is rotated.
+func (c *SharedKafkaAdminClient) SetConfig(config *kafka.ConfigMap) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.config = config
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// Close closes the underlying client, the shared client can not be used after it is closed.
+func (c *SharedKafkaAdminClient) Close() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	c.closed = true
+}
+
+// callKafkaAdmin calls the underlying client, the underlying client is not closed until the call returns. If the call
+// returns a fatal error, the underlying client is closed, so the next call reconnects to the broker with a new client.
+// The latency of the call is recorded with the given operation.
+func callKafkaAdmin[T any](ctx context.Context, c *SharedKafkaAdminClient, operation string,
+	call func(client KafkaAdminClient) (T, error)) (T, error) {
+	var result T
+
+	start := time.Now()
+	client, err := c.acquire()
+	if err != nil {
+		observeKafkaAdminCall(operation, start, err)
+		return result, err
+	}
+
+	result, err = call(client)
+	c.RUnlock()
+	observeKafkaAdminCall(operation, start, err)
+
+	if isFatalKafkaAdminError(err) {
+		klog.FromContext(ctx).Info(fmt.Sprintf("the kafka admin client will be recreated, %v", err))
+		c.invalidate(client)
+	}
+
+	return result, err
+}
+
+// acquire returns the underlying client with the read lock held, the caller must release the read lock.
+func (c *SharedKafkaAdminClient) acquire() (KafkaAdminClient, error) {
+	for {
+		c.RLock()
+		if c.closed {
+			c.RUnlock()
+			return nil, fmt.Errorf("the kafka admin client is closed")
+		}
+		if c.client != nil {
+			return c.client, nil
+		}
+		c.RUnlock()
+
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *SharedKafkaAdminClient) connect() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.closed || c.client != nil {
+		return nil
+	}
+
+	client, err := c.newClient(c.config)
+	if err != nil {
+		return err
+	}
+
+	c.client = client
+	return nil
+}
+
+// invalidate closes the given client if it still is the underlying client.
+func (c *SharedKafkaAdminClient) invalidate(client KafkaAdminClient) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client != client {
+		return
+	}
+
+	c.client.Close()
+	c.client = nil
+}
+
+// isFatalKafkaAdminError returns true if the admin client can not recover from the given error by itself, the
+// librdkafka reconnects the broker for the transport errors, so they are not fatal.
+func isFatalKafkaAdminError(err error) bool {
+	var kafkaErr kafka.Error
+	if !errors.As(err, &kafkaErr) {
+		return false
+	}
+
+	if kafkaErr.IsFatal() {
+		return true
+	}
+
+	switch kafkaErr.Code() {
+	case kafka.ErrAuthentication, kafka.ErrSsl, kafka.ErrState, kafka.ErrDestroy:
+		return true
+	default:
+		return false
+	}
+}
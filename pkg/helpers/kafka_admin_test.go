@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+type failingKafkaAdminClient struct {
+	*mock.KafkaAdminMockClient
+	err error
+}
+
+func (c *failingKafkaAdminClient) DescribeTopics(ctx context.Context, topics kafka.TopicCollection,
+	options ...kafka.DescribeTopicsAdminOption) (kafka.DescribeTopicsResult, error) {
+	if c.err != nil {
+		return kafka.DescribeTopicsResult{}, c.err
+	}
+	return c.KafkaAdminMockClient.DescribeTopics(ctx, topics, options...)
+}
+
+func TestSharedKafkaAdminClient(t *testing.T) {
+	cases := []struct {
+		name              string
+		err               error
+		expectedClients   int
+		expectedReconnect bool
+	}{
+		{
+			name:            "reuse the client",
+			expectedClients: 1,
+		},
+		{
+			name:            "reuse the client after a transport error",
+			err:             kafka.NewError(kafka.ErrTransport, "broker transport failure", false),
+			expectedClients: 1,
+		},
+		{
+			name:              "reconnect after a fatal error",
+			err:               kafka.NewError(kafka.ErrFatal, "fatal error", true),
+			expectedClients:   2,
+			expectedReconnect: true,
+		},
+		{
+			name:              "reconnect after an authentication error",
+			err:               kafka.NewError(kafka.ErrAuthentication, "authentication failed", false),
+			expectedClients:   2,
+			expectedReconnect: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clients := []*failingKafkaAdminClient{}
+			sharedClient := &SharedKafkaAdminClient{
+				newClient: func(config *kafka.ConfigMap) (KafkaAdminClient, error) {
+					client := &failingKafkaAdminClient{KafkaAdminMockClient: mock.NewKafkaAdminMockClient()}
+					if len(clients) == 0 {
+						client.err = c.err
+					}
+					clients = append(clients, client)
+					return client, nil
+				},
+			}
+
+			for i := 0; i < 2; i++ {
+				_, _ = sharedClient.DescribeTopics(context.Background(), kafka.NewTopicCollectionOfTopicNames(kafkaTopics()))
+			}
+
+			if len(clients) != c.expectedClients {
+				t.Errorf("expected %d clients, but got %d", c.expectedClients, len(clients))
+			}
+			if clients[0].Closed() != c.expectedReconnect {
+				t.Errorf("expected the first client closed %v, but got %v", c.expectedReconnect, clients[0].Closed())
+			}
+
+			sharedClient.Close()
+			for _, client := range clients {
+				if !client.Closed() {
+					t.Errorf("expected the clients are closed")
+				}
+			}
+
+			if _, err := sharedClient.DescribeTopics(context.Background(),
+				kafka.NewTopicCollectionOfTopicNames(kafkaTopics())); err == nil {
+				t.Errorf("expected an error after the client is closed")
+			}
+		})
+	}
+}
+
+func TestSharedKafkaAdminClientSetConfig(t *testing.T) {
+	configs := []*kafka.ConfigMap{}
+	clients := []*mock.KafkaAdminMockClient{}
+	sharedClient := &SharedKafkaAdminClient{
+		config: &kafka.ConfigMap{"bootstrap.servers": "kafka:9092"},
+		newClient: func(config *kafka.ConfigMap) (KafkaAdminClient, error) {
+			client := mock.NewKafkaAdminMockClient()
+			configs = append(configs, config)
+			clients = append(clients, client)
+			return client, nil
+		},
+	}
+	defer sharedClient.Close()
+
+	topics := kafka.NewTopicCollectionOfTopicNames(kafkaTopics())
+	if _, err := sharedClient.DescribeTopics(context.Background(), topics); err != nil {
+		t.Fatal(err)
+	}
+
+	sharedClient.SetConfig(&kafka.ConfigMap{"bootstrap.servers": "kafka-new:9092"})
+	if !clients[0].Closed() {
+		t.Errorf("expected the client of the old config is closed")
+	}
+
+	if _, err := sharedClient.DescribeTopics(context.Background(), topics); err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("expected 2 clients, but got %d", len(configs))
+	}
+	if server, _ := configs[1].Get("bootstrap.servers", ""); server != "kafka-new:9092" {
+		t.Errorf("expected the client is recreated with the new config, but got %v", server)
+	}
+}
@@ -0,0 +1,216 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// agentGroupSuffix is the suffix of the consumer group of the agent, the agent of a cluster joins the consumer group
+// <cluster>-work-agent.
+const agentGroupSuffix = "work-agent"
+
+// KafkaConsumerGroupStatus is the status of the consumer group of an agent, including its lag on the source events topic.
+type KafkaConsumerGroupStatus struct {
+	// GroupID is the consumer group of the agent.
+	GroupID string
+	// State is the state of the consumer group, e.g. Stable, Empty or Dead.
+	State kafka.ConsumerGroupState
+	// Members is the number of the active members of the consumer group.
+	Members int
+	// Lag is the sum of the messages that are not consumed by the consumer group on the partitions of the source
+	// events topic that the consumer group has committed.
+	Lag int64
+}
+
+// ToAgentGroupID returns the consumer group of the agent of the given cluster.
+func ToAgentGroupID(clusterName string) string {
+	return fmt.Sprintf("%s-%s", clusterName, agentGroupSuffix)
+}
+
+// DescribeAgentConsumerGroupsLag describes the consumer groups of the agents of the given clusters and computes their
+// lag on the source events topic with the committed offsets and the latest offsets of the topic partitions. The
+// consumer groups are described with one request and the latest offsets of all committed partitions are listed with
+// one request, the committed offsets are listed for each consumer group, because the admin client only supports one
+// consumer group in a ListConsumerGroupOffsets request. The clusters whose lag cannot be computed are not in the result,
+// their errors are aggregated.
+func DescribeAgentConsumerGroupsLag(ctx context.Context, adminClient KafkaAdminClient,
+	clusterNames []string) (map[string]KafkaConsumerGroupStatus, error) {
+	statuses, err := DescribeAgentConsumerGroups(ctx, adminClient, clusterNames)
+	if len(statuses) == 0 {
+		return statuses, err
+	}
+
+	errs := []error{}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// the committed offsets of the clusters on the partitions of the source events topic
+	committed := map[string]map[kafkaTopicPartition]kafka.Offset{}
+	latestSpecs := map[kafka.TopicPartition]kafka.OffsetSpec{}
+	listed := sets.New[kafkaTopicPartition]()
+	for _, clusterName := range sets.List(sets.KeySet(statuses)) {
+		groupID := statuses[clusterName].GroupID
+		offsets, err := listCommittedOffsets(ctx, adminClient, groupID, sourceEventsTopic)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list the offsets of the consumer group %s: %v", groupID, err))
+			delete(statuses, clusterName)
+			continue
+		}
+
+		committed[clusterName] = offsets
+		for partition := range offsets {
+			if listed.Has(partition) {
+				continue
+			}
+			listed.Insert(partition)
+			topic := partition.topic
+			latestSpecs[kafka.TopicPartition{Topic: &topic, Partition: partition.partition}] = kafka.LatestOffsetSpec
+		}
+	}
+
+	if len(latestSpecs) == 0 {
+		return statuses, errors.NewAggregate(errs)
+	}
+
+	latestOffsets, err := adminClient.ListOffsets(ctx, latestSpecs)
+	if err != nil {
+		// the lag of the clusters that have committed offsets is unknown
+		for clusterName, offsets := range committed {
+			if len(offsets) != 0 {
+				delete(statuses, clusterName)
+			}
+		}
+		return statuses, errors.NewAggregate(append(errs, err))
+	}
+
+	latest := map[kafkaTopicPartition]kafka.Offset{}
+	for partition, info := range latestOffsets.ResultInfos {
+		if partition.Topic == nil {
+			continue
+		}
+		key := kafkaTopicPartition{topic: *partition.Topic, partition: partition.Partition}
+		if info.Error.Code() != kafka.ErrNoError {
+			errs = append(errs, fmt.Errorf("failed to list the latest offset of the partition %d of the topic %s: %v",
+				key.partition, key.topic, info.Error))
+			continue
+		}
+		latest[key] = info.Offset
+	}
+
+	for clusterName, offsets := range committed {
+		lag, ok := toKafkaLag(offsets, latest)
+		if !ok {
+			// the lag of the cluster is unknown without the latest offsets of its partitions
+			delete(statuses, clusterName)
+			continue
+		}
+
+		status := statuses[clusterName]
+		status.Lag = lag
+		statuses[clusterName] = status
+	}
+
+	return statuses, errors.NewAggregate(errs)
+}
+
+// DescribeAgentConsumerGroups describes the states and the active members of the consumer groups of the agents of the
+// given clusters with one request, the offsets are not listed, so the lag of the returned statuses is not set. The
+// clusters whose consumer groups cannot be described are not in the result, their errors are aggregated.
+func DescribeAgentConsumerGroups(ctx context.Context, adminClient KafkaAdminClient,
+	clusterNames []string) (map[string]KafkaConsumerGroupStatus, error) {
+	statuses := map[string]KafkaConsumerGroupStatus{}
+	if len(clusterNames) == 0 {
+		return statuses, nil
+	}
+
+	groupClusters := map[string]string{}
+	groupIDs := []string{}
+	for _, clusterName := range clusterNames {
+		groupID := ToAgentGroupID(clusterName)
+		groupClusters[groupID] = clusterName
+		groupIDs = append(groupIDs, groupID)
+	}
+
+	groups, err := adminClient.DescribeConsumerGroups(ctx, groupIDs)
+	if err != nil {
+		return statuses, err
+	}
+
+	errs := []error{}
+	for _, group := range groups.ConsumerGroupDescriptions {
+		clusterName, ok := groupClusters[group.GroupID]
+		if !ok {
+			continue
+		}
+		if group.Error.Code() != kafka.ErrNoError {
+			errs = append(errs, fmt.Errorf("failed to describe the consumer group %s: %v", group.GroupID, group.Error))
+			continue
+		}
+
+		statuses[clusterName] = KafkaConsumerGroupStatus{
+			GroupID: group.GroupID,
+			State:   group.State,
+			Members: len(group.Members),
+		}
+	}
+
+	return statuses, errors.NewAggregate(errs)
+}
+
+// kafkaTopicPartition identifies a topic partition by the topic name, the topic of a kafka.TopicPartition is a pointer,
+// so it cannot be compared across the requests.
+type kafkaTopicPartition struct {
+	topic     string
+	partition int32
+}
+
+// listCommittedOffsets lists the committed offsets of the given consumer group on the partitions of the given topic,
+// the partitions without a committed offset are not returned.
+func listCommittedOffsets(ctx context.Context, adminClient KafkaAdminClient,
+	groupID, topic string) (map[kafkaTopicPartition]kafka.Offset, error) {
+	groupOffsets, err := adminClient.ListConsumerGroupOffsets(ctx, []kafka.ConsumerGroupTopicPartitions{{Group: groupID}})
+	if err != nil {
+		return nil, err
+	}
+
+	committed := map[kafkaTopicPartition]kafka.Offset{}
+	for _, group := range groupOffsets.ConsumerGroupsTopicPartitions {
+		for _, partition := range group.Partitions {
+			if partition.Topic == nil || *partition.Topic != topic {
+				continue
+			}
+			if partition.Error != nil {
+				return nil, partition.Error
+			}
+			// the partition does not have a committed offset
+			if partition.Offset < 0 {
+				continue
+			}
+
+			committed[kafkaTopicPartition{topic: topic, partition: partition.Partition}] = partition.Offset
+		}
+	}
+
+	return committed, nil
+}
+
+// toKafkaLag returns the sum of the differences between the latest offsets and the committed offsets of the given
+// partitions, it returns false if the latest offset of a partition is unknown.
+func toKafkaLag(committed, latest map[kafkaTopicPartition]kafka.Offset) (int64, bool) {
+	var lag int64
+	for partition, offset := range committed {
+		latestOffset, ok := latest[partition]
+		if !ok {
+			return 0, false
+		}
+		if latestOffset > offset {
+			lag += int64(latestOffset - offset)
+		}
+	}
+	return lag, true
+}
@@ -0,0 +1,100 @@
+package helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestDescribeAgentConsumerGroups(t *testing.T) {
+	client := mock.NewKafkaAdminMockClient()
+	client.SetConsumerGroup("cluster1-work-agent", 2)
+	client.SetConsumerGroupOffset("cluster1-work-agent", "sourceevents", 0, 10)
+	client.SetLatestOffset("sourceevents", 0, 15)
+
+	statuses, err := DescribeAgentConsumerGroups(context.Background(), client, []string{"cluster1", "cluster2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]KafkaConsumerGroupStatus{
+		"cluster1": {GroupID: "cluster1-work-agent", State: kafka.ConsumerGroupStateStable, Members: 2},
+		"cluster2": {GroupID: "cluster2-work-agent", State: kafka.ConsumerGroupStateDead},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected statuses %v, but got %v", expected, statuses)
+	}
+
+	// only the consumer groups are described, the offsets are not listed
+	if calls := client.DescribeConsumerGroupsCalls(); calls != 1 {
+		t.Errorf("expected 1 describe consumer groups call, but got %d", calls)
+	}
+	if calls := client.ListConsumerGroupOffsetsCalls() + client.ListOffsetsCalls(); calls != 0 {
+		t.Errorf("expected no list offsets calls, but got %d", calls)
+	}
+}
+
+func TestDescribeAgentConsumerGroupsLag(t *testing.T) {
+	cases := []struct {
+		name                string
+		prepare             func(client *mock.KafkaAdminMockClient)
+		expectedStatuses    map[string]KafkaConsumerGroupStatus
+		expectedListOffsets int
+	}{
+		{
+			name:    "the consumer groups do not exist",
+			prepare: func(client *mock.KafkaAdminMockClient) {},
+			expectedStatuses: map[string]KafkaConsumerGroupStatus{
+				"cluster1": {GroupID: "cluster1-work-agent", State: kafka.ConsumerGroupStateDead},
+				"cluster2": {GroupID: "cluster2-work-agent", State: kafka.ConsumerGroupStateDead},
+			},
+		},
+		{
+			name: "the consumer groups lag",
+			prepare: func(client *mock.KafkaAdminMockClient) {
+				client.SetConsumerGroup("cluster1-work-agent", 1)
+				client.SetConsumerGroupOffset("cluster1-work-agent", "sourceevents", 0, 10)
+				client.SetConsumerGroupOffset("cluster1-work-agent", "sourceevents", 1, 20)
+				// the offsets on the other topics are not counted
+				client.SetConsumerGroupOffset("cluster1-work-agent", "agentevents", 0, 0)
+				client.SetConsumerGroupOffset("cluster2-work-agent", "sourceevents", 0, 12)
+				client.SetLatestOffset("sourceevents", 0, 15)
+				client.SetLatestOffset("sourceevents", 1, 20)
+				client.SetLatestOffset("agentevents", 0, 100)
+			},
+			expectedStatuses: map[string]KafkaConsumerGroupStatus{
+				"cluster1": {GroupID: "cluster1-work-agent", State: kafka.ConsumerGroupStateStable, Members: 1, Lag: 5},
+				"cluster2": {GroupID: "cluster2-work-agent", State: kafka.ConsumerGroupStateEmpty, Lag: 3},
+			},
+			expectedListOffsets: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := mock.NewKafkaAdminMockClient()
+			c.prepare(client)
+
+			statuses, err := DescribeAgentConsumerGroupsLag(context.Background(), client, []string{"cluster1", "cluster2"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(statuses, c.expectedStatuses) {
+				t.Errorf("expected statuses %v, but got %v", c.expectedStatuses, statuses)
+			}
+
+			// the consumer groups and the latest offsets of all clusters are requested at once
+			if calls := client.DescribeConsumerGroupsCalls(); calls != 1 {
+				t.Errorf("expected 1 describe consumer groups call, but got %d", calls)
+			}
+			if calls := client.ListOffsetsCalls(); calls != c.expectedListOffsets {
+				t.Errorf("expected %d list offsets calls, but got %d", c.expectedListOffsets, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+)
+
+// KafkaPrincipalPreset is a predefined template of the agent certificate principal, the principal must match the
+// principal that the broker maps from the agent certificate with its ssl.principal.mapping.rules.
+type KafkaPrincipalPreset string
+
+const (
+	// KafkaPrincipalPresetDN is the principal of the broker default mapping rule, it is the full distinguished name
+	// of the agent certificate, e.g. User:CN=<common name>,O=<group1>+O=<group2>+O=<group3>.
+	KafkaPrincipalPresetDN KafkaPrincipalPreset = "dn"
+
+	// KafkaPrincipalPresetCN is the principal of a mapping rule that only keeps the common name of the agent
+	// certificate, e.g. RULE:^CN=([^,]+),.*$/$1/, the principal is User:<common name>.
+	KafkaPrincipalPresetCN KafkaPrincipalPreset = "cn"
+)
+
+var kafkaPrincipalPresetTemplates = map[KafkaPrincipalPreset]string{
+	KafkaPrincipalPresetDN: `User:CN={{ .CommonName }},{{ range $i, $group := .Groups }}{{ if $i }}+{{ end }}O={{ $group }}{{ end }}`,
+	KafkaPrincipalPresetCN: `User:{{ .CommonName }}`,
+}
+
+var defaultKafkaPrincipalTemplate = template.Must(
+	template.New("principal").Option("missingkey=error").Parse(kafkaPrincipalPresetTemplates[KafkaPrincipalPresetDN]))
+
+// KafkaPrincipalValues are the values that can be used in the principal template.
+type KafkaPrincipalValues struct {
+	// ClusterName is the name of the managed cluster.
+	ClusterName string
+	// AddOnName is the name of the addon, it is maestro-addon.
+	AddOnName string
+	// CommonName is the common name of the agent certificate.
+	CommonName string
+	// Groups are the organizations of the agent certificate.
+	Groups []string
+}
+
+// NewKafkaPrincipalTemplate parses the principal template, the given text takes precedence over the preset, the
+// dn preset is used if both of them are empty.
+func NewKafkaPrincipalTemplate(preset KafkaPrincipalPreset, text string) (*template.Template, error) {
+	if text == "" {
+		switch preset {
+		case "":
+			return defaultKafkaPrincipalTemplate, nil
+		case KafkaPrincipalPresetDN, KafkaPrincipalPresetCN:
+			text = kafkaPrincipalPresetTemplates[preset]
+		default:
+			return nil, fmt.Errorf("unsupported principal preset %q, it must be %s or %s",
+				preset, KafkaPrincipalPresetDN, KafkaPrincipalPresetCN)
+		}
+	}
+
+	tmpl, err := template.New("principal").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the principal template: %v", err)
+	}
+
+	// render the template with a sample cluster to find the errors earlier
+	if _, err := ToKafkaPrincipal(tmpl, "cluster1"); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// ToKafkaPrincipal renders the principal of the agent certificate of the given cluster with the given template,
+// the default dn template is used if the template is nil.
+func ToKafkaPrincipal(tmpl *template.Template, clusterName string) (string, error) {
+	if tmpl == nil {
+		tmpl = defaultKafkaPrincipalTemplate
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, toKafkaPrincipalValues(clusterName)); err != nil {
+		return "", fmt.Errorf("failed to render the principal template: %v", err)
+	}
+
+	principal := buf.String()
+	if !strings.HasPrefix(principal, "User:") || len(principal) == len("User:") {
+		return "", fmt.Errorf("the principal %q must be in the format User:<name>", principal)
+	}
+
+	return principal, nil
+}
+
+func toKafkaPrincipalValues(clusterName string) KafkaPrincipalValues {
+	return KafkaPrincipalValues{
+		ClusterName: clusterName,
+		AddOnName:   common.AddOnName,
+		CommonName:  ToAgentCommonName(clusterName),
+		Groups: []string{
+			"system:authenticated",
+			fmt.Sprintf("system:open-cluster-management:addon:%s", common.AddOnName),
+			fmt.Sprintf("system:open-cluster-management:cluster:%s:addon:%s", clusterName, common.AddOnName),
+		},
+	}
+}
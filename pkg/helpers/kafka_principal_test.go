@@ -0,0 +1,77 @@
+package helpers
+
+import "testing"
+
+func TestToKafkaPrincipalWithTemplate(t *testing.T) {
+	cases := []struct {
+		name              string
+		preset            KafkaPrincipalPreset
+		text              string
+		expectedError     bool
+		expectedPrincipal string
+	}{
+		{
+			name:              "default",
+			expectedPrincipal: toKafkaPrincipal("cluster1"),
+		},
+		{
+			name:              "dn preset",
+			preset:            KafkaPrincipalPresetDN,
+			expectedPrincipal: toKafkaPrincipal("cluster1"),
+		},
+		{
+			name:              "cn preset",
+			preset:            KafkaPrincipalPresetCN,
+			expectedPrincipal: "User:system:open-cluster-management:cluster:cluster1:addon:maestro-addon:agent:maestro-addon-agent",
+		},
+		{
+			name:              "template takes precedence over preset",
+			preset:            KafkaPrincipalPresetCN,
+			text:              "User:{{ .ClusterName }}-{{ .AddOnName }}",
+			expectedPrincipal: "User:cluster1-maestro-addon",
+		},
+		{
+			name:          "unsupported preset",
+			preset:        "uid",
+			expectedError: true,
+		},
+		{
+			name:          "invalid template",
+			text:          "User:{{ .ClusterName",
+			expectedError: true,
+		},
+		{
+			name:          "unknown field",
+			text:          "User:{{ .Namespace }}",
+			expectedError: true,
+		},
+		{
+			name:          "principal type is required",
+			text:          "{{ .CommonName }}",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tmpl, err := NewKafkaPrincipalTemplate(c.preset, c.text)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			principal, err := ToKafkaPrincipal(tmpl, "cluster1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if principal != c.expectedPrincipal {
+				t.Errorf("expected principal %s, but got %s", c.expectedPrincipal, principal)
+			}
+		})
+	}
+}
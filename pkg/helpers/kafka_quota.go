@@ -0,0 +1,298 @@
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"golang.org/x/oauth2/clientcredentials"
+	"k8s.io/klog/v2"
+)
+
+const (
+	kafkaQuotaProducerByteRate  = "producer_byte_rate"
+	kafkaQuotaConsumerByteRate  = "consumer_byte_rate"
+	kafkaQuotaRequestPercentage = "request_percentage"
+)
+
+// KafkaClientQuotas are the client quotas of an agent, a nil quota is not limited.
+type KafkaClientQuotas struct {
+	// ProducerByteRate is the maximum bytes per second that the agent can publish.
+	ProducerByteRate *int64 `json:"producerByteRate,omitempty" yaml:"producerByteRate,omitempty"`
+	// ConsumerByteRate is the maximum bytes per second that the agent can consume.
+	ConsumerByteRate *int64 `json:"consumerByteRate,omitempty" yaml:"consumerByteRate,omitempty"`
+	// RequestPercentage is the maximum percentage of the time that the broker request handler and network threads
+	// spend on the requests of the agent.
+	RequestPercentage *int64 `json:"requestPercentage,omitempty" yaml:"requestPercentage,omitempty"`
+}
+
+// IsEmpty returns true if none of the quotas is set.
+func (q KafkaClientQuotas) IsEmpty() bool {
+	return q.ProducerByteRate == nil && q.ConsumerByteRate == nil && q.RequestPercentage == nil
+}
+
+// Merge returns the quotas that are overridden by the set quotas of the given overrides.
+func (q KafkaClientQuotas) Merge(overrides KafkaClientQuotas) KafkaClientQuotas {
+	if overrides.ProducerByteRate != nil {
+		q.ProducerByteRate = overrides.ProducerByteRate
+	}
+	if overrides.ConsumerByteRate != nil {
+		q.ConsumerByteRate = overrides.ConsumerByteRate
+	}
+	if overrides.RequestPercentage != nil {
+		q.RequestPercentage = overrides.RequestPercentage
+	}
+	return q
+}
+
+// Validate validates the quotas are positive and the request percentage is not greater than 100.
+func (q KafkaClientQuotas) Validate() error {
+	for name, value := range map[string]*int64{
+		"producerByteRate":  q.ProducerByteRate,
+		"consumerByteRate":  q.ConsumerByteRate,
+		"requestPercentage": q.RequestPercentage,
+	} {
+		if value != nil && *value <= 0 {
+			return fmt.Errorf("the %s quota must be positive", name)
+		}
+	}
+
+	if q.RequestPercentage != nil && *q.RequestPercentage > 100 {
+		return fmt.Errorf("the requestPercentage quota must not be greater than 100")
+	}
+
+	return nil
+}
+
+// ParseKafkaClientQuotas parses the quotas from a comma-separated list of key=value pairs, e.g.
+// producerByteRate=1048576,consumerByteRate=2097152,requestPercentage=50.
+func ParseKafkaClientQuotas(text string) (KafkaClientQuotas, error) {
+	quotas := KafkaClientQuotas{}
+	for _, pair := range strings.Split(text, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return quotas, fmt.Errorf("the quota %q must be in the format key=value", pair)
+		}
+
+		quota, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return quotas, fmt.Errorf("the value of the quota %q must be an integer", pair)
+		}
+
+		switch strings.TrimSpace(key) {
+		case "producerByteRate":
+			quotas.ProducerByteRate = &quota
+		case "consumerByteRate":
+			quotas.ConsumerByteRate = &quota
+		case "requestPercentage":
+			quotas.RequestPercentage = &quota
+		default:
+			return quotas, fmt.Errorf("unsupported quota %q, it must be producerByteRate, consumerByteRate or requestPercentage", key)
+		}
+	}
+
+	return quotas, quotas.Validate()
+}
+
+// AlterClientQuotas sets the client quotas of the agent of the given cluster, the quotas that are not set are removed.
+func AlterClientQuotas(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions,
+	clusterName string, quotas KafkaClientQuotas) error {
+	principal, err := ToAgentPrincipal(opts, clusterName)
+	if err != nil {
+		return err
+	}
+
+	// the user entity of the quotas is the principal name without the User: prefix
+	if err := adminClient.AlterClientQuotas(ctx, strings.TrimPrefix(principal, "User:"), toKafkaQuotaValues(quotas)); err != nil {
+		return err
+	}
+
+	klog.FromContext(ctx).V(4).Info(fmt.Sprintf("client quotas is altered successfully for %s", principal))
+	return nil
+}
+
+// DeleteClientQuotas removes the client quotas of the agent of the given cluster.
+func DeleteClientQuotas(ctx context.Context, adminClient KafkaAdminClient, opts KafkaACLOptions, clusterName string) error {
+	return AlterClientQuotas(ctx, adminClient, opts, clusterName, KafkaClientQuotas{})
+}
+
+// toKafkaQuotaValues returns the values of the Kafka quota keys, the value of a quota that is not set is nil.
+func toKafkaQuotaValues(quotas KafkaClientQuotas) map[string]*float64 {
+	toValue := func(quota *int64) *float64 {
+		if quota == nil {
+			return nil
+		}
+		value := float64(*quota)
+		return &value
+	}
+
+	return map[string]*float64{
+		kafkaQuotaProducerByteRate:  toValue(quotas.ProducerByteRate),
+		kafkaQuotaConsumerByteRate:  toValue(quotas.ConsumerByteRate),
+		kafkaQuotaRequestPercentage: toValue(quotas.RequestPercentage),
+	}
+}
+
+// kafkaAdminClient adds the client quota API to the kafka.AdminClient. The confluent-kafka-go (librdkafka) does not
+// provide the client quota API, so the quotas are altered with the AlterClientQuotas request of the Kafka admin
+// protocol, it is sent by a franz-go client that connects to the broker with the same config.
+type kafkaAdminClient struct {
+	*kafka.AdminClient
+	quotaClient *kadm.Client
+}
+
+// NewKafkaAdminClient returns an admin client with the given config.
+func NewKafkaAdminClient(config *kafka.ConfigMap) (KafkaAdminClient, error) {
+	opts, err := toKafkaProtocolOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	quotaClient, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	adminClient, err := kafka.NewAdminClient(config)
+	if err != nil {
+		quotaClient.Close()
+		return nil, err
+	}
+
+	return &kafkaAdminClient{AdminClient: adminClient, quotaClient: kadm.NewClient(quotaClient)}, nil
+}
+
+// AlterClientQuotas sets the client quotas of the given user entity, the quotas with nil values are removed.
+func (c *kafkaAdminClient) AlterClientQuotas(ctx context.Context, user string, quotas map[string]*float64) error {
+	entry := kadm.AlterClientQuotaEntry{
+		Entity: kadm.ClientQuotaEntity{{Type: "user", Name: &user}},
+	}
+	for key, value := range quotas {
+		if value == nil {
+			entry.Ops = append(entry.Ops, kadm.AlterClientQuotaOp{Key: key, Remove: true})
+			continue
+		}
+		entry.Ops = append(entry.Ops, kadm.AlterClientQuotaOp{Key: key, Value: *value})
+	}
+
+	results, err := c.quotaClient.AlterClientQuotas(ctx, []kadm.AlterClientQuotaEntry{entry})
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("failed to alter the client quotas of %s: %v %s", user, result.Err, result.ErrMessage)
+		}
+	}
+	return nil
+}
+
+func (c *kafkaAdminClient) Close() {
+	c.quotaClient.Close()
+	c.AdminClient.Close()
+}
+
+// toKafkaProtocolOptions maps the librdkafka properties that are set by the message queue config to the franz-go
+// client options, so the quota client uses the same bootstrap server, TLS and SASL settings as the admin client.
+func toKafkaProtocolOptions(config *kafka.ConfigMap) ([]kgo.Opt, error) {
+	getString := func(key string) string {
+		value, err := config.Get(key, "")
+		if err != nil {
+			return ""
+		}
+		text, _ := value.(string)
+		return text
+	}
+
+	opts := []kgo.Opt{kgo.SeedBrokers(strings.Split(getString("bootstrap.servers"), ",")...)}
+
+	protocol := strings.ToLower(getString("security.protocol"))
+	switch protocol {
+	case "", "plaintext", "sasl_plaintext":
+	case "ssl", "sasl_ssl":
+		tlsConfig, err := toKafkaTLSConfig(getString("ssl.ca.location"),
+			getString("ssl.certificate.location"), getString("ssl.key.location"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	default:
+		return nil, fmt.Errorf("unsupported security.protocol %q", protocol)
+	}
+
+	if !strings.HasPrefix(protocol, "sasl_") {
+		return opts, nil
+	}
+
+	username, password := getString("sasl.username"), getString("sasl.password")
+	switch mechanism := getString("sasl.mechanism"); mechanism {
+	case "PLAIN":
+		opts = append(opts, kgo.SASL(plain.Auth{User: username, Pass: password}.AsMechanism()))
+	case "SCRAM-SHA-256":
+		opts = append(opts, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha256Mechanism()))
+	case "SCRAM-SHA-512":
+		opts = append(opts, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha512Mechanism()))
+	case "OAUTHBEARER":
+		tokenConfig := &clientcredentials.Config{
+			ClientID:     getString("sasl.oauthbearer.client.id"),
+			ClientSecret: getString("sasl.oauthbearer.client.secret"),
+			TokenURL:     getString("sasl.oauthbearer.token.endpoint.url"),
+			Scopes:       strings.Fields(getString("sasl.oauthbearer.scope")),
+		}
+		opts = append(opts, kgo.SASL(oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+			token, err := tokenConfig.Token(ctx)
+			if err != nil {
+				return oauth.Auth{}, err
+			}
+			return oauth.Auth{Token: token.AccessToken}, nil
+		})))
+	default:
+		return nil, fmt.Errorf("unsupported sasl.mechanism %q", mechanism)
+	}
+
+	return opts, nil
+}
+
+// toKafkaTLSConfig returns the TLS config with the given CA and client certificate files, the system CAs are used if
+// the CA file is not set, and the client certificate is only used if it is set.
+func toKafkaTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		caData, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates are found in the CA file %s", caFile)
+		}
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
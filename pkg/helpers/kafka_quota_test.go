@@ -0,0 +1,175 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestParseKafkaClientQuotas(t *testing.T) {
+	producerByteRate, requestPercentage := int64(1048576), int64(50)
+
+	cases := []struct {
+		name           string
+		text           string
+		expectedQuotas KafkaClientQuotas
+		expectedError  bool
+	}{
+		{
+			name: "empty",
+			text: "",
+		},
+		{
+			name:           "quotas",
+			text:           "producerByteRate=1048576, requestPercentage=50",
+			expectedQuotas: KafkaClientQuotas{ProducerByteRate: &producerByteRate, RequestPercentage: &requestPercentage},
+		},
+		{
+			name:          "invalid format",
+			text:          "producerByteRate:1048576",
+			expectedError: true,
+		},
+		{
+			name:          "not an integer",
+			text:          "consumerByteRate=1MiB",
+			expectedError: true,
+		},
+		{
+			name:          "unsupported quota",
+			text:          "controllerMutationRate=10",
+			expectedError: true,
+		},
+		{
+			name:          "not positive",
+			text:          "producerByteRate=0",
+			expectedError: true,
+		},
+		{
+			name:          "request percentage is greater than 100",
+			text:          "requestPercentage=101",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			quotas, err := ParseKafkaClientQuotas(c.text)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equality.Semantic.DeepEqual(quotas, c.expectedQuotas) {
+				t.Errorf("expected quotas %v, but got %v", c.expectedQuotas, quotas)
+			}
+		})
+	}
+}
+
+func TestAlterClientQuotas(t *testing.T) {
+	producerByteRate, consumerByteRate := int64(1048576), int64(2097152)
+	opts := KafkaACLOptions{AgentAuthentication: KafkaAgentAuthenticationScram}
+	user := "cluster1-maestro-addon-agent"
+
+	client := mock.NewKafkaAdminMockClient()
+	if err := AlterClientQuotas(context.Background(), client, opts, "cluster1", KafkaClientQuotas{
+		ProducerByteRate: &producerByteRate,
+		ConsumerByteRate: &consumerByteRate,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]float64{"producer_byte_rate": 1048576, "consumer_byte_rate": 2097152}
+	if !equality.Semantic.DeepEqual(client.ClientQuotas(user), expected) {
+		t.Errorf("expected quotas %v, but got %v", expected, client.ClientQuotas(user))
+	}
+
+	// the quota that is not set is removed
+	if err := AlterClientQuotas(context.Background(), client, opts, "cluster1", KafkaClientQuotas{
+		ProducerByteRate: &producerByteRate,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = map[string]float64{"producer_byte_rate": 1048576}
+	if !equality.Semantic.DeepEqual(client.ClientQuotas(user), expected) {
+		t.Errorf("expected quotas %v, but got %v", expected, client.ClientQuotas(user))
+	}
+
+	if err := DeleteClientQuotas(context.Background(), client, opts, "cluster1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.ClientQuotas(user)) != 0 {
+		t.Errorf("expected the quotas are deleted, but got %v", client.ClientQuotas(user))
+	}
+}
+
+func TestToKafkaProtocolOptions(t *testing.T) {
+	cases := []struct {
+		name          string
+		config        *kafka.ConfigMap
+		expectedError bool
+	}{
+		{
+			name:   "plaintext",
+			config: &kafka.ConfigMap{"bootstrap.servers": "kafka:9092"},
+		},
+		{
+			name: "sasl plaintext with scram",
+			config: &kafka.ConfigMap{
+				"bootstrap.servers": "kafka:9092",
+				"security.protocol": "sasl_plaintext",
+				"sasl.mechanism":    "SCRAM-SHA-512",
+				"sasl.username":     "admin",
+				"sasl.password":     "password",
+			},
+		},
+		{
+			name: "ssl without ca file",
+			config: &kafka.ConfigMap{
+				"bootstrap.servers": "kafka:9093",
+				"security.protocol": "ssl",
+				"ssl.ca.location":   "/not/found/ca.crt",
+			},
+			expectedError: true,
+		},
+		{
+			name: "unsupported protocol",
+			config: &kafka.ConfigMap{
+				"bootstrap.servers": "kafka:9092",
+				"security.protocol": "tcp",
+			},
+			expectedError: true,
+		},
+		{
+			name: "unsupported mechanism",
+			config: &kafka.ConfigMap{
+				"bootstrap.servers": "kafka:9092",
+				"security.protocol": "sasl_plaintext",
+				"sasl.mechanism":    "GSSAPI",
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := toKafkaProtocolOptions(c.config)
+			if c.expectedError && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedError && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
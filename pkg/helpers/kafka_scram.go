@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+)
+
+// the default iterations of the SCRAM credentials, it is the minimum iterations that Kafka allows.
+const scramIterations = 4096
+
+// EnsureScramCredential creates the SCRAM credential of the given user with the given password, if the credential
+// already exists, it will be updated only when overwrite is true.
+func EnsureScramCredential(ctx context.Context, adminClient KafkaAdminClient,
+	mechanism kafka.ScramMechanism, user, password string, overwrite bool) error {
+	return ensureScramCredential(ctx, adminClient, mechanism, user, password, overwrite)
+}
+
+// DeleteScramCredential deletes the SCRAM credential of the given user.
+func DeleteScramCredential(ctx context.Context, adminClient KafkaAdminClient, mechanism kafka.ScramMechanism, user string) error {
+	return deleteScramCredential(ctx, adminClient, mechanism, user)
+}
+
+// ToKafkaScramUser returns the SCRAM user name of the agent of the given cluster.
+func ToKafkaScramUser(clusterName string) string {
+	return fmt.Sprintf("%s-%s-agent", clusterName, common.AddOnName)
+}
+
+func ensureScramCredential(ctx context.Context, adminClient KafkaAdminClient,
+	mechanism kafka.ScramMechanism, user, password string, overwrite bool) error {
+	logger := klog.FromContext(ctx)
+
+	if !overwrite {
+		existed, err := hasScramCredential(ctx, adminClient, mechanism, user)
+		if err != nil {
+			return err
+		}
+
+		if existed {
+			logger.V(4).Info(fmt.Sprintf("scram credential %s already exists for %s", mechanism, user))
+			return nil
+		}
+	}
+
+	result, err := adminClient.AlterUserScramCredentials(ctx, []kafka.UserScramCredentialUpsertion{{
+		User: user,
+		ScramCredentialInfo: kafka.ScramCredentialInfo{
+			Iterations: scramIterations,
+			Mechanism:  mechanism,
+		},
+		Password: []byte(password),
+	}}, nil)
+	if err != nil {
+		return err
+	}
+
+	if e, ok := result.Errors[user]; ok && e.Code() != kafka.ErrNoError {
+		return fmt.Errorf("failed to upsert scram credential for %s, %s", user, e.String())
+	}
+
+	logger.V(4).Info(fmt.Sprintf("scram credential %s is upserted successfully for %s", mechanism, user))
+	return nil
+}
+
+func deleteScramCredential(ctx context.Context, adminClient KafkaAdminClient, mechanism kafka.ScramMechanism, user string) error {
+	logger := klog.FromContext(ctx)
+
+	result, err := adminClient.AlterUserScramCredentials(ctx, nil, []kafka.UserScramCredentialDeletion{{
+		User:      user,
+		Mechanism: mechanism,
+	}})
+	if err != nil {
+		return err
+	}
+
+	e, ok := result.Errors[user]
+	if !ok {
+		return nil
+	}
+
+	switch e.Code() {
+	case kafka.ErrNoError:
+		logger.V(2).Info(fmt.Sprintf("scram credential %s is deleted for %s", mechanism, user))
+	case kafka.ErrResourceNotFound:
+		logger.V(2).Info(fmt.Sprintf("scram credential %s is already absent for %s", mechanism, user))
+	default:
+		return fmt.Errorf("failed to delete scram credential for %s, %s", user, e.String())
+	}
+
+	return nil
+}
+
+func hasScramCredential(ctx context.Context, adminClient KafkaAdminClient, mechanism kafka.ScramMechanism, user string) (bool, error) {
+	result, err := adminClient.DescribeUserScramCredentials(ctx, []string{user})
+	if err != nil {
+		return false, err
+	}
+
+	description, ok := result.Descriptions[user]
+	if !ok {
+		return false, nil
+	}
+
+	switch description.Error.Code() {
+	case kafka.ErrNoError:
+	case kafka.ErrResourceNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to describe scram credentials for %s, %s", user, description.Error.String())
+	}
+
+	for _, info := range description.ScramCredentialInfos {
+		if info.Mechanism == mechanism {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,89 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestEnsureScramCredential(t *testing.T) {
+	user := ToKafkaScramUser("cluster1")
+
+	cases := []struct {
+		name             string
+		initPassword     string
+		overwrite        bool
+		expectedPassword string
+	}{
+		{
+			name:             "create scram credential",
+			expectedPassword: "new",
+		},
+		{
+			name:             "keep existing scram credential",
+			initPassword:     "old",
+			expectedPassword: "old",
+		},
+		{
+			name:             "overwrite existing scram credential",
+			initPassword:     "old",
+			overwrite:        true,
+			expectedPassword: "new",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := mock.NewKafkaAdminMockClient()
+			if c.initPassword != "" {
+				if err := ensureScramCredential(context.Background(), client,
+					kafka.ScramMechanismSHA512, user, c.initPassword, false); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := ensureScramCredential(context.Background(), client,
+				kafka.ScramMechanismSHA512, user, "new", c.overwrite); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if client.ScramPassword(user) != c.expectedPassword {
+				t.Errorf("expected password %s, but got %s", c.expectedPassword, client.ScramPassword(user))
+			}
+		})
+	}
+}
+
+func TestDeleteScramCredential(t *testing.T) {
+	user := ToKafkaScramUser("cluster1")
+	client := mock.NewKafkaAdminMockClient()
+	if err := ensureScramCredential(context.Background(), client, kafka.ScramMechanismSHA512, user, "password", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// delete twice to make sure deleting an absent credential is ok
+	for i := 0; i < 2; i++ {
+		if err := deleteScramCredential(context.Background(), client, kafka.ScramMechanismSHA512, user); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if client.ScramPassword(user) != "" {
+		t.Errorf("expected credential is deleted")
+	}
+}
+
+func TestToAgentPrincipal(t *testing.T) {
+	scramPrincipal, err := ToAgentPrincipal(KafkaACLOptions{AgentAuthentication: KafkaAgentAuthenticationScram}, "cluster1")
+	if err != nil || scramPrincipal != "User:cluster1-maestro-addon-agent" {
+		t.Errorf("unexpected principal: %s, %v", scramPrincipal, err)
+	}
+
+	certPrincipal, err := ToAgentPrincipal(KafkaACLOptions{AgentAuthentication: KafkaAgentAuthenticationCertificate}, "cluster1")
+	if err != nil || certPrincipal != toKafkaPrincipal("cluster1") {
+		t.Errorf("unexpected principal: %s, %v", certPrincipal, err)
+	}
+}
@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// KafkaSourceACLOptions are the options to create the ACLs of a source (e.g. the Maestro server).
+type KafkaSourceACLOptions struct {
+	// Principal is the principal of the source.
+	Principal string
+
+	// GroupID is the consumer group of the source.
+	GroupID string
+
+	// RemoveStaleACLs removes the ACLs of the source principal that are not in the expected ACLs.
+	RemoveStaleACLs bool
+}
+
+// CreateSourceACLs creates the least privilege ACLs of a source, so the source does not need to be a super user.
+func CreateSourceACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaSourceACLOptions) error {
+	return createSourceKafkaACLs(ctx, adminClient, opts)
+}
+
+func createSourceKafkaACLs(ctx context.Context, adminClient KafkaAdminClient, opts KafkaSourceACLOptions) error {
+	return ensureKafkaACLs(ctx, adminClient, opts.Principal, sourceKafkaACLBindings(opts), opts.RemoveStaleACLs)
+}
+
+// sourceKafkaACLBindings returns the ACLs of the source, the source publishes the source events, subscribes the
+// agent events and reads with its own consumer group.
+func sourceKafkaACLBindings(opts KafkaSourceACLOptions) []kafka.ACLBinding {
+	sourceTopic, agentTopic := sourceEventsTopic, agentEventsTopic
+
+	principal := opts.Principal
+	return []kafka.ACLBinding{
+		// the source publishes the source events
+		newKafkaACLBinding(principal, kafka.ResourceTopic, sourceTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationWrite),
+		newKafkaACLBinding(principal, kafka.ResourceTopic, sourceTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationDescribe),
+		// the source subscribes the agent events
+		newKafkaACLBinding(principal, kafka.ResourceTopic, agentTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationRead),
+		newKafkaACLBinding(principal, kafka.ResourceTopic, agentTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationDescribe),
+		// the source only can join its own consumer group
+		newKafkaACLBinding(principal, kafka.ResourceGroup, opts.GroupID, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationRead),
+		newKafkaACLBinding(principal, kafka.ResourceGroup, opts.GroupID, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationDescribe),
+	}
+}
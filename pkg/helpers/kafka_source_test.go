@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestCreateSourceKafkaACLs(t *testing.T) {
+	principal := "User:CN=maestro"
+
+	cases := []struct {
+		name                string
+		options             KafkaSourceACLOptions
+		initACLs            kafka.ACLBindings
+		expectedPatternType kafka.ResourcePatternType
+		expectedTopics      []string
+	}{
+		{
+			name:                "source acls",
+			options:             KafkaSourceACLOptions{Principal: principal, GroupID: "maestro"},
+			expectedPatternType: kafka.ResourcePatternTypeLiteral,
+			expectedTopics:      []string{"sourceevents", "agentevents"},
+		},
+		{
+			name: "remove stale acls",
+			options: KafkaSourceACLOptions{
+				Principal: principal, GroupID: "maestro", RemoveStaleACLs: true},
+			initACLs: kafka.ACLBindings{
+				newKafkaACLBinding(principal, kafka.ResourceTopic, "*", kafka.ResourcePatternTypeLiteral, kafka.ACLOperationAll),
+			},
+			expectedPatternType: kafka.ResourcePatternTypeLiteral,
+			expectedTopics:      []string{"sourceevents", "agentevents"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := mock.NewKafkaAdminMockClient()
+			if len(c.initACLs) != 0 {
+				if _, err := client.CreateACLs(context.Background(), c.initACLs); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := createSourceKafkaACLs(context.Background(), client, c.options); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			acls := client.ACLBindings()
+			if len(acls) != 6 {
+				t.Fatalf("expected 6 acls, but got %v", acls)
+			}
+
+			for _, acl := range acls {
+				switch acl.Type {
+				case kafka.ResourceTopic:
+					if acl.ResourcePatternType != c.expectedPatternType {
+						t.Errorf("unexpected topic acl %v", acl)
+					}
+					if acl.Name != c.expectedTopics[0] && acl.Name != c.expectedTopics[1] {
+						t.Errorf("unexpected topic acl %v", acl)
+					}
+					if acl.Name == c.expectedTopics[0] && acl.Operation == kafka.ACLOperationRead {
+						t.Errorf("unexpected read acl on the source topic %v", acl)
+					}
+					if acl.Name == c.expectedTopics[1] && acl.Operation == kafka.ACLOperationWrite {
+						t.Errorf("unexpected write acl on the agent topic %v", acl)
+					}
+				case kafka.ResourceGroup:
+					if acl.Name != "maestro" || acl.ResourcePatternType != kafka.ResourcePatternTypeLiteral {
+						t.Errorf("unexpected group acl %v", acl)
+					}
+				default:
+					t.Errorf("unexpected acl %v", acl)
+				}
+			}
+		})
+	}
+}
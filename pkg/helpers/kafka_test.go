@@ -3,49 +3,279 @@ package helpers
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
 	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
 )
 
 func TestCreateKafkaTopics(t *testing.T) {
 	cases := []struct {
-		name           string
-		topics         []string
-		intiTopics     []string
-		expectedTopics []string
+		name               string
+		specs              KafkaTopicSpecs
+		intiTopics         []string
+		expectedTopics     []string
+		expectedPartitions int
 	}{
 		{
-			name:           "create place holder topics",
-			topics:         kafkaTopics(),
-			intiTopics:     []string{},
-			expectedTopics: kafkaTopics(),
+			name:               "create place holder topics",
+			intiTopics:         []string{},
+			expectedTopics:     kafkaTopics(),
+			expectedPartitions: sharedTopicPartitions,
+		},
+		{
+			name: "create topics with specified partitions",
+			specs: KafkaTopicSpecs{
+				SourceEvents: kafka.TopicSpecification{NumPartitions: 10, ReplicationFactor: 3},
+				AgentEvents:  kafka.TopicSpecification{NumPartitions: 10, ReplicationFactor: 3},
+			},
+			intiTopics:         []string{},
+			expectedTopics:     kafkaTopics(),
+			expectedPartitions: 10,
+		},
+		{
+			name:               "expand partitions of existing topics",
+			intiTopics:         kafkaTopics(),
+			expectedTopics:     kafkaTopics(),
+			expectedPartitions: sharedTopicPartitions,
 		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			client := mock.NewKafkaAdminMockClient(c.intiTopics...)
-			if err := createKafkaTopics(context.Background(), client, c.topics...); err != nil {
+			specs := toKafkaTopicSpecifications(c.specs)
+			if err := createKafkaTopics(context.Background(), client, specs...); err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
 
 			if !reflect.DeepEqual(client.Topics(), c.expectedTopics) {
 				t.Errorf("expected %v, but got %v", c.expectedTopics, client.Topics())
 			}
+
+			for _, topic := range c.expectedTopics {
+				if client.Partitions(topic) != c.expectedPartitions {
+					t.Errorf("expected %d partitions of %s, but got %d", c.expectedPartitions, topic, client.Partitions(topic))
+				}
+			}
 		})
 	}
 }
 
 func TestCreateKafkaACLs(t *testing.T) {
+	principal := toKafkaPrincipal("cluster")
+
+	cases := []struct {
+		name         string
+		initACLs     kafka.ACLBindings
+		options      KafkaACLOptions
+		expectedACLs []string
+	}{
+		{
+			name:         "create restricted acls",
+			options:      KafkaACLOptions{Profile: KafkaACLProfileRestricted},
+			expectedACLs: []string{"sourceevents", "sourceevents", "agentevents", "agentevents", "cluster-work-agent", "cluster-work-agent"},
+		},
+		{
+			name:         "create permissive acls",
+			options:      KafkaACLOptions{Profile: KafkaACLProfilePermissive},
+			expectedACLs: append([]string{"*"}, kafkaTopics()...),
+		},
+		{
+			name: "a deny acl on the same topic does not mask the missing allow acl",
+			initACLs: kafka.ACLBindings{
+				{
+					Type:                kafka.ResourceTopic,
+					Name:                sourceEventsTopic,
+					ResourcePatternType: kafka.ResourcePatternTypeLiteral,
+					Principal:           principal,
+					Host:                "*",
+					Operation:           kafka.ACLOperationAll,
+					PermissionType:      kafka.ACLPermissionTypeDeny,
+				},
+			},
+			options:      KafkaACLOptions{Profile: KafkaACLProfilePermissive},
+			expectedACLs: []string{"sourceevents", "*", "sourceevents", "agentevents"},
+		},
+		{
+			name: "remove stale acls",
+			initACLs: kafka.ACLBindings{
+				newKafkaACLBinding(principal, kafka.ResourceGroup, "*", kafka.ResourcePatternTypeLiteral, kafka.ACLOperationAll),
+				newKafkaACLBinding(principal, kafka.ResourceTopic, sourceEventsTopic, kafka.ResourcePatternTypeLiteral, kafka.ACLOperationRead),
+				newKafkaACLBinding(toKafkaPrincipal("other"), kafka.ResourceGroup, "*", kafka.ResourcePatternTypeLiteral, kafka.ACLOperationAll),
+			},
+			options:      KafkaACLOptions{Profile: KafkaACLProfileRestricted, RemoveStaleACLs: true},
+			expectedACLs: []string{"sourceevents", "*", "sourceevents", "agentevents", "agentevents", "cluster-work-agent", "cluster-work-agent"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := mock.NewKafkaAdminMockClient()
+			if len(c.initACLs) != 0 {
+				if _, err := client.CreateACLs(context.Background(), c.initACLs); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := createKafkaACLs(context.Background(), client, c.options,
+				"cluster", sourceEventsTopic, agentEventsTopic); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(client.ACLs(), c.expectedACLs) {
+				t.Errorf("expected %v, but got %v", c.expectedACLs, client.ACLs())
+			}
+
+			// the existing acls of the principal are fetched with one call
+			if client.DescribeACLsCalls() != 1 {
+				t.Errorf("expected 1 describe acls call, but got %d", client.DescribeACLsCalls())
+			}
+		})
+	}
+}
+
+func TestCreateBulkKafkaACLs(t *testing.T) {
+	client := mock.NewKafkaAdminMockClient()
+	opts := KafkaACLOptions{Profile: KafkaACLProfileRestricted}
+
+	// the acls of cluster1 already exist
+	if err := CreateACLs(context.Background(), client, opts, "cluster1"); err != nil {
+		t.Fatal(err)
+	}
+	describeCalls, createCalls := client.DescribeACLsCalls(), client.CreateACLsCalls()
+
+	if err := CreateBulkACLs(context.Background(), client, opts, []string{"cluster1", "cluster2", "cluster3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := client.DescribeACLsCalls() - describeCalls; calls != 3 {
+		t.Errorf("expected 3 describe acls calls, but got %d", calls)
+	}
+	if calls := client.CreateACLsCalls() - createCalls; calls != 1 {
+		t.Errorf("expected 1 create acls call, but got %d", calls)
+	}
+
+	for _, clusterName := range []string{"cluster1", "cluster2", "cluster3"} {
+		expected, err := KafkaACLBindings(opts, clusterName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, acl := range expected {
+			if !containsKafkaACL(client.ACLBindings(), acl) {
+				t.Errorf("expected acl %v for %s", acl, clusterName)
+			}
+		}
+	}
+
+	if len(client.ACLBindings()) != 18 {
+		t.Errorf("expected 18 acls, but got %d", len(client.ACLBindings()))
+	}
+}
+
+func TestRestrictedKafkaACLs(t *testing.T) {
+	client := mock.NewKafkaAdminMockClient()
+	if err := createKafkaACLs(context.Background(), client, KafkaACLOptions{Profile: KafkaACLProfileRestricted},
+		"cluster", sourceEventsTopic, agentEventsTopic); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, acl := range client.ACLBindings() {
+		if acl.Operation == kafka.ACLOperationAll {
+			t.Errorf("unexpected acl %v", acl)
+		}
+
+		if acl.Type == kafka.ResourceTopic && acl.Name == sourceEventsTopic && acl.Operation == kafka.ACLOperationWrite {
+			t.Errorf("unexpected write acl on source topic %v", acl)
+		}
+
+		if acl.Type == kafka.ResourceGroup &&
+			(acl.ResourcePatternType != kafka.ResourcePatternTypeLiteral || acl.Name != "cluster-work-agent") {
+			t.Errorf("unexpected group acl %v", acl)
+		}
+	}
+}
+
+func TestRestrictedKafkaGroupACLs(t *testing.T) {
+	// the name of the cluster a is a prefix of the name of the cluster a-b
+	client := mock.NewKafkaAdminMockClient()
+	for _, clusterName := range []string{"a", "a-b"} {
+		if err := CreateACLs(context.Background(), client, KafkaACLOptions{Profile: KafkaACLProfileRestricted}, clusterName); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// allowsGroup returns true if the principal is allowed to read the group with the created acls
+	allowsGroup := func(principal, group string) bool {
+		for _, acl := range client.ACLBindings() {
+			if acl.Principal != principal || acl.Type != kafka.ResourceGroup || acl.Operation != kafka.ACLOperationRead {
+				continue
+			}
+
+			switch acl.ResourcePatternType {
+			case kafka.ResourcePatternTypeLiteral:
+				if acl.Name == group || acl.Name == "*" {
+					return true
+				}
+			case kafka.ResourcePatternTypePrefixed:
+				if strings.HasPrefix(group, acl.Name) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	cases := []struct {
+		clusterName string
+		group       string
+		expected    bool
+	}{
+		{clusterName: "a", group: "a-work-agent", expected: true},
+		{clusterName: "a", group: "a-b-work-agent", expected: false},
+		{clusterName: "a-b", group: "a-b-work-agent", expected: true},
+		{clusterName: "a-b", group: "a-work-agent", expected: false},
+	}
+	for _, c := range cases {
+		if allowed := allowsGroup(toKafkaPrincipal(c.clusterName), c.group); allowed != c.expected {
+			t.Errorf("expected the agent of the cluster %s is allowed to read the group %s: %v, but got %v",
+				c.clusterName, c.group, c.expected, allowed)
+		}
+	}
+}
+
+func TestDeleteKafkaACLs(t *testing.T) {
 	cases := []struct {
 		name         string
-		topics       []string
+		initClusters []string
+		profile      KafkaACLProfile
 		expectedACLs []string
 	}{
 		{
-			name:         "create kafka cluster topics",
-			topics:       kafkaTopics(),
+			name:         "delete restricted acls of the cluster",
+			initClusters: []string{"cluster"},
+			profile:      KafkaACLProfileRestricted,
+			expectedACLs: []string{},
+		},
+		{
+			name:         "delete permissive acls of the cluster",
+			initClusters: []string{"cluster"},
+			profile:      KafkaACLProfilePermissive,
+			expectedACLs: []string{},
+		},
+		{
+			name:         "delete absent acls",
+			initClusters: []string{},
+			profile:      KafkaACLProfileRestricted,
+			expectedACLs: []string{},
+		},
+		{
+			name:         "acls of other clusters are kept",
+			initClusters: []string{"cluster", "other"},
+			profile:      KafkaACLProfilePermissive,
 			expectedACLs: append([]string{"*"}, kafkaTopics()...),
 		},
 	}
@@ -53,7 +283,15 @@ func TestCreateKafkaACLs(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			client := mock.NewKafkaAdminMockClient()
-			if err := createKafkaACLs(context.Background(), client, "cluster", c.topics...); err != nil {
+			for _, cluster := range c.initClusters {
+				if err := createKafkaACLs(context.Background(), client, KafkaACLOptions{Profile: c.profile},
+					cluster, sourceEventsTopic, agentEventsTopic); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := deleteKafkaACLs(context.Background(), client, KafkaACLOptions{},
+				"cluster", sourceEventsTopic, agentEventsTopic); err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
 
@@ -74,3 +312,14 @@ func TestToKafkaPrincipal(t *testing.T) {
 		t.Errorf("unexpected principal: %s", toKafkaPrincipal("cluster1"))
 	}
 }
+
+// toKafkaPrincipal returns the principal of the agent certificate with the default dn template.
+func toKafkaPrincipal(clusterName string) string {
+	principal, _ := ToKafkaPrincipal(nil, clusterName)
+	return principal
+}
+
+// kafkaTopics returns the names of the shared event topics.
+func kafkaTopics() []string {
+	return []string{sourceEventsTopic, agentEventsTopic}
+}
@@ -22,27 +22,26 @@ func NewMaestroAPIClient(maestroServerAddress string) *openapi.APIClient {
 		},
 		OperationServers: map[string]openapi.ServerConfigurations{},
 		HTTPClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: &maestroAPIMetricsTransport{next: http.DefaultTransport},
 		},
 	}
 	return openapi.NewAPIClient(cfg)
 }
 
+// PingMaestroAPI lists one consumer from the maestro to verify that the maestro API is reachable.
+func PingMaestroAPI(ctx context.Context, client *openapi.APIClient) error {
+	_, _, err := client.DefaultApi.ApiMaestroV1ConsumersGet(ctx).Size(1).Execute()
+	return err
+}
+
 func FindConsumerByName(ctx context.Context, client *openapi.APIClient, consumerName string) (bool, error) {
-	list, _, err := client.DefaultApi.ApiMaestroV1ConsumersGet(ctx).
-		Search(fmt.Sprintf("name = '%s'", consumerName)).
-		Execute()
+	consumer, err := getConsumerByName(ctx, client, consumerName)
 	if err != nil {
 		return false, err
 	}
 
-	for _, consumer := range list.Items {
-		if *consumer.Name == consumerName {
-			return true, nil
-		}
-	}
-
-	return false, nil
+	return consumer != nil, nil
 }
 
 func CreateConsumer(ctx context.Context, client *openapi.APIClient, consumerName string) error {
@@ -51,3 +50,38 @@ func CreateConsumer(ctx context.Context, client *openapi.APIClient, consumerName
 		Execute()
 	return err
 }
+
+// DeleteConsumer deletes the consumer with the given name from the maestro, it returns false if the
+// consumer does not exist.
+func DeleteConsumer(ctx context.Context, client *openapi.APIClient, consumerName string) (bool, error) {
+	consumer, err := getConsumerByName(ctx, client, consumerName)
+	if err != nil {
+		return false, err
+	}
+
+	if consumer == nil || consumer.Id == nil {
+		return false, nil
+	}
+
+	if _, err = client.DefaultApi.ApiMaestroV1ConsumersIdDelete(ctx, *consumer.Id).Execute(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func getConsumerByName(ctx context.Context, client *openapi.APIClient, consumerName string) (*openapi.Consumer, error) {
+	list, _, err := client.DefaultApi.ApiMaestroV1ConsumersGet(ctx).
+		Search(fmt.Sprintf("name = '%s'", consumerName)).
+		Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, consumer := range list.Items {
+		if *consumer.Name == consumerName {
+			return &consumer, nil
+		}
+	}
+
+	return nil, nil
+}
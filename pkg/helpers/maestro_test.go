@@ -54,3 +54,37 @@ func TestCreateConsumer(t *testing.T) {
 	}
 
 }
+
+func TestDeleteConsumer(t *testing.T) {
+	maestroServer := mock.NewMaestroMockServer()
+	maestroServer.Start()
+	defer maestroServer.Stop()
+
+	cases := []struct {
+		name            string
+		consumer        string
+		expectedDeleted bool
+	}{
+		{
+			name:            "delete an existed consumer",
+			consumer:        mock.Consumer,
+			expectedDeleted: true,
+		},
+		{
+			name:     "delete a nonexistent consumer",
+			consumer: "cluster1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			deleted, err := DeleteConsumer(context.Background(), NewMaestroAPIClient(maestroServer.URL()), c.consumer)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if deleted != c.expectedDeleted {
+				t.Errorf("expected deleted %v, but got %v", c.expectedDeleted, deleted)
+			}
+		})
+	}
+}
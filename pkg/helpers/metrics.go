@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var maestroAPIRequestDuration = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Name:    "maestro_addon_maestro_api_request_duration_seconds",
+		Help:    "The latency of the Maestro API requests by the endpoint and the response code.",
+		Buckets: metrics.DefBuckets,
+	},
+	[]string{"endpoint", "code"},
+)
+
+var kafkaAdminCallDuration = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Name:    "maestro_addon_kafka_admin_call_duration_seconds",
+		Help:    "The latency of the Kafka admin API calls by the operation and the result.",
+		Buckets: metrics.DefBuckets,
+	},
+	[]string{"operation", "result"},
+)
+
+func init() {
+	legacyregistry.MustRegister(maestroAPIRequestDuration)
+	legacyregistry.MustRegister(kafkaAdminCallDuration)
+}
+
+// maestroAPIMetricsTransport records the latency of the Maestro API requests.
+type maestroAPIMetricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *maestroAPIMetricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	maestroAPIRequestDuration.WithLabelValues(toMaestroAPIEndpoint(req), code).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
+
+// toMaestroAPIEndpoint returns the method and the path of the request, the resource ID in the path is replaced with
+// {id}, so the requests of different resources have the same endpoint, e.g. DELETE /api/maestro/v1/consumers/{id}.
+func toMaestroAPIEndpoint(req *http.Request) string {
+	// the path is /api/maestro/v1/<resources>/<id>
+	segments := strings.Split(req.URL.Path, "/")
+	if len(segments) > 5 && len(segments[5]) != 0 {
+		segments[5] = "{id}"
+	}
+	return req.Method + " " + strings.Join(segments, "/")
+}
+
+// observeKafkaAdminCall records the latency of a Kafka admin API call.
+func observeKafkaAdminCall(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	kafkaAdminCallDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestToMaestroAPIEndpoint(t *testing.T) {
+	cases := []struct {
+		name             string
+		method           string
+		url              string
+		expectedEndpoint string
+	}{
+		{
+			name:             "list consumers",
+			method:           http.MethodGet,
+			url:              "http://maestro:8000/api/maestro/v1/consumers?search=name+%3D+%27cluster1%27",
+			expectedEndpoint: "GET /api/maestro/v1/consumers",
+		},
+		{
+			name:             "create consumer",
+			method:           http.MethodPost,
+			url:              "http://maestro:8000/api/maestro/v1/consumers",
+			expectedEndpoint: "POST /api/maestro/v1/consumers",
+		},
+		{
+			name:             "delete consumer",
+			method:           http.MethodDelete,
+			url:              "http://maestro:8000/api/maestro/v1/consumers/2f0e1b4c",
+			expectedEndpoint: "DELETE /api/maestro/v1/consumers/{id}",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			endpoint := toMaestroAPIEndpoint(httptest.NewRequest(c.method, c.url, nil))
+			if endpoint != c.expectedEndpoint {
+				t.Errorf("expected endpoint %q, but got %q", c.expectedEndpoint, endpoint)
+			}
+		})
+	}
+}
+
+func TestObserveKafkaAdminCall(t *testing.T) {
+	sharedClient := &SharedKafkaAdminClient{
+		config: &kafka.ConfigMap{"bootstrap.servers": "kafka:9092"},
+		newClient: func(config *kafka.ConfigMap) (KafkaAdminClient, error) {
+			return &failingKafkaAdminClient{
+				KafkaAdminMockClient: mock.NewKafkaAdminMockClient(),
+				err:                  kafka.NewError(kafka.ErrTransport, "broker down", false),
+			}, nil
+		},
+	}
+	defer sharedClient.Close()
+
+	failures := kafkaAdminCallDuration.WithLabelValues("DescribeTopics", "error")
+	failureCount, _ := testutil.GetHistogramMetricCount(failures)
+
+	topics := kafka.NewTopicCollectionOfTopicNames(kafkaTopics())
+	if _, err := sharedClient.DescribeTopics(context.Background(), topics); err == nil {
+		t.Fatalf("expected the call is failed")
+	}
+
+	if count, _ := testutil.GetHistogramMetricCount(failures); count != failureCount+1 {
+		t.Errorf("expected the failed call is recorded, but got %d", count-failureCount)
+	}
+}
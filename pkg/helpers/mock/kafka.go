@@ -2,13 +2,39 @@ package mock
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
 type KafkaAdminMockClient struct {
-	topics kafka.DescribeTopicsResult
-	acls   *kafka.DescribeACLsResult
+	topics           kafka.DescribeTopicsResult
+	acls             *kafka.DescribeACLsResult
+	scramCredentials map[string]scramCredential
+	closed           bool
+
+	describeACLsCalls     int
+	createACLsCalls       int
+	describeGroupsCalls   int
+	listGroupOffsetsCalls int
+	listOffsetsCalls      int
+
+	clientQuotas map[string]map[string]float64
+
+	consumerGroups map[string]*consumerGroup
+	latestOffsets  map[string]map[int32]int64
+
+	describeClusterErr error
+}
+
+type consumerGroup struct {
+	members int
+	offsets map[string]map[int32]int64
+}
+
+type scramCredential struct {
+	mechanism kafka.ScramMechanism
+	password  string
 }
 
 func NewKafkaAdminMockClient(initTopics ...string) *KafkaAdminMockClient {
@@ -28,6 +54,10 @@ func NewKafkaAdminMockClient(initTopics ...string) *KafkaAdminMockClient {
 			ACLBindings: kafka.ACLBindings{},
 			Error:       kafka.NewError(kafka.ErrNoError, "", false),
 		},
+		scramCredentials: map[string]scramCredential{},
+		clientQuotas:     map[string]map[string]float64{},
+		consumerGroups:   map[string]*consumerGroup{},
+		latestOffsets:    map[string]map[int32]int64{},
 	}
 }
 
@@ -38,15 +68,26 @@ func (m *KafkaAdminMockClient) DescribeTopics(ctx context.Context, topics kafka.
 
 func (m *KafkaAdminMockClient) DescribeACLs(ctx context.Context, aclBindingFilter kafka.ACLBindingFilter,
 	options ...kafka.DescribeACLsAdminOption) (result *kafka.DescribeACLsResult, err error) {
-	return m.acls, nil
+	m.describeACLsCalls++
+	result = &kafka.DescribeACLsResult{
+		ACLBindings: kafka.ACLBindings{},
+		Error:       m.acls.Error,
+	}
+	for _, binding := range m.acls.ACLBindings {
+		if matchACLBindingFilter(aclBindingFilter, binding) {
+			result.ACLBindings = append(result.ACLBindings, binding)
+		}
+	}
+	return result, nil
 }
 
 func (m *KafkaAdminMockClient) CreateTopics(ctx context.Context, topics []kafka.TopicSpecification,
 	options ...kafka.CreateTopicsAdminOption) (result []kafka.TopicResult, err error) {
 	for _, topic := range topics {
 		m.topics.TopicDescriptions = append(m.topics.TopicDescriptions, kafka.TopicDescription{
-			Name:  topic.Topic,
-			Error: kafka.NewError(kafka.ErrNoError, "", false),
+			Name:       topic.Topic,
+			Error:      kafka.NewError(kafka.ErrNoError, "", false),
+			Partitions: make([]kafka.TopicPartitionInfo, topic.NumPartitions),
 		})
 
 		result = append(result, kafka.TopicResult{
@@ -59,6 +100,7 @@ func (m *KafkaAdminMockClient) CreateTopics(ctx context.Context, topics []kafka.
 
 func (m *KafkaAdminMockClient) CreateACLs(ctx context.Context, aclBindings kafka.ACLBindings,
 	options ...kafka.CreateACLsAdminOption) (result []kafka.CreateACLResult, err error) {
+	m.createACLsCalls++
 	for _, binding := range aclBindings {
 		m.acls.ACLBindings = append(m.acls.ACLBindings, binding)
 		result = append(result, kafka.CreateACLResult{
@@ -68,6 +110,258 @@ func (m *KafkaAdminMockClient) CreateACLs(ctx context.Context, aclBindings kafka
 	return result, nil
 }
 
+func (m *KafkaAdminMockClient) DeleteACLs(ctx context.Context, aclBindingFilters kafka.ACLBindingFilters,
+	options ...kafka.DeleteACLsAdminOption) (result []kafka.DeleteACLsResult, err error) {
+	for _, filter := range aclBindingFilters {
+		deleted := kafka.ACLBindings{}
+		remaining := kafka.ACLBindings{}
+		for _, binding := range m.acls.ACLBindings {
+			if matchACLBindingFilter(filter, binding) {
+				deleted = append(deleted, binding)
+				continue
+			}
+			remaining = append(remaining, binding)
+		}
+
+		m.acls.ACLBindings = remaining
+		result = append(result, kafka.DeleteACLsResult{
+			ACLBindings: deleted,
+			Error:       kafka.NewError(kafka.ErrNoError, "", false),
+		})
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) CreatePartitions(ctx context.Context, partitions []kafka.PartitionsSpecification,
+	options ...kafka.CreatePartitionsAdminOption) (result []kafka.TopicResult, err error) {
+	for _, partition := range partitions {
+		code := kafka.ErrUnknownTopicOrPart
+		for i, t := range m.topics.TopicDescriptions {
+			if t.Name != partition.Topic {
+				continue
+			}
+
+			code = kafka.ErrInvalidPartitions
+			if partition.IncreaseTo > len(t.Partitions) {
+				code = kafka.ErrNoError
+				m.topics.TopicDescriptions[i].Partitions = make([]kafka.TopicPartitionInfo, partition.IncreaseTo)
+			}
+		}
+
+		result = append(result, kafka.TopicResult{
+			Topic: partition.Topic,
+			Error: kafka.NewError(code, "", false),
+		})
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) DescribeUserScramCredentials(ctx context.Context, users []string,
+	options ...kafka.DescribeUserScramCredentialsAdminOption) (result kafka.DescribeUserScramCredentialsResult, err error) {
+	result.Descriptions = map[string]kafka.UserScramCredentialsDescription{}
+	for _, user := range users {
+		credential, ok := m.scramCredentials[user]
+		if !ok {
+			result.Descriptions[user] = kafka.UserScramCredentialsDescription{
+				User:  user,
+				Error: kafka.NewError(kafka.ErrResourceNotFound, "", false),
+			}
+			continue
+		}
+
+		result.Descriptions[user] = kafka.UserScramCredentialsDescription{
+			User:                 user,
+			ScramCredentialInfos: []kafka.ScramCredentialInfo{{Mechanism: credential.mechanism}},
+			Error:                kafka.NewError(kafka.ErrNoError, "", false),
+		}
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) AlterUserScramCredentials(ctx context.Context, upsertions []kafka.UserScramCredentialUpsertion,
+	deletions []kafka.UserScramCredentialDeletion,
+	options ...kafka.AlterUserScramCredentialsAdminOption) (result kafka.AlterUserScramCredentialsResult, err error) {
+	result.Errors = map[string]kafka.Error{}
+	for _, upsertion := range upsertions {
+		m.scramCredentials[upsertion.User] = scramCredential{
+			mechanism: upsertion.ScramCredentialInfo.Mechanism,
+			password:  string(upsertion.Password),
+		}
+		result.Errors[upsertion.User] = kafka.NewError(kafka.ErrNoError, "", false)
+	}
+	for _, deletion := range deletions {
+		if _, ok := m.scramCredentials[deletion.User]; !ok {
+			result.Errors[deletion.User] = kafka.NewError(kafka.ErrResourceNotFound, "", false)
+			continue
+		}
+		delete(m.scramCredentials, deletion.User)
+		result.Errors[deletion.User] = kafka.NewError(kafka.ErrNoError, "", false)
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) AlterClientQuotas(ctx context.Context, user string, quotas map[string]*float64) error {
+	for key, value := range quotas {
+		if value == nil {
+			delete(m.clientQuotas[user], key)
+			continue
+		}
+
+		if _, ok := m.clientQuotas[user]; !ok {
+			m.clientQuotas[user] = map[string]float64{}
+		}
+		m.clientQuotas[user][key] = *value
+	}
+
+	if len(m.clientQuotas[user]) == 0 {
+		delete(m.clientQuotas, user)
+	}
+	return nil
+}
+
+func (m *KafkaAdminMockClient) DescribeConsumerGroups(ctx context.Context, groups []string,
+	options ...kafka.DescribeConsumerGroupsAdminOption) (result kafka.DescribeConsumerGroupsResult, err error) {
+	m.describeGroupsCalls++
+	for _, groupID := range groups {
+		description := kafka.ConsumerGroupDescription{
+			GroupID: groupID,
+			Error:   kafka.NewError(kafka.ErrNoError, "", false),
+			State:   kafka.ConsumerGroupStateDead,
+		}
+
+		if group, ok := m.consumerGroups[groupID]; ok {
+			description.State = kafka.ConsumerGroupStateEmpty
+			if group.members > 0 {
+				description.State = kafka.ConsumerGroupStateStable
+			}
+			for i := 0; i < group.members; i++ {
+				description.Members = append(description.Members, kafka.MemberDescription{
+					ClientID: fmt.Sprintf("%s-%d", groupID, i),
+				})
+			}
+		}
+
+		result.ConsumerGroupDescriptions = append(result.ConsumerGroupDescriptions, description)
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) ListConsumerGroupOffsets(ctx context.Context, groupsPartitions []kafka.ConsumerGroupTopicPartitions,
+	options ...kafka.ListConsumerGroupOffsetsAdminOption) (result kafka.ListConsumerGroupOffsetsResult, err error) {
+	m.listGroupOffsetsCalls++
+	for _, groupPartitions := range groupsPartitions {
+		partitions := []kafka.TopicPartition{}
+		if group, ok := m.consumerGroups[groupPartitions.Group]; ok {
+			for topic, offsets := range group.offsets {
+				for partition, offset := range offsets {
+					topic := topic
+					partitions = append(partitions, kafka.TopicPartition{
+						Topic:     &topic,
+						Partition: partition,
+						Offset:    kafka.Offset(offset),
+					})
+				}
+			}
+		}
+
+		result.ConsumerGroupsTopicPartitions = append(result.ConsumerGroupsTopicPartitions,
+			kafka.ConsumerGroupTopicPartitions{Group: groupPartitions.Group, Partitions: partitions})
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) ListOffsets(ctx context.Context, topicPartitionOffsets map[kafka.TopicPartition]kafka.OffsetSpec,
+	options ...kafka.ListOffsetsAdminOption) (result kafka.ListOffsetsResult, err error) {
+	m.listOffsetsCalls++
+	result.ResultInfos = map[kafka.TopicPartition]kafka.ListOffsetsResultInfo{}
+	for topicPartition := range topicPartitionOffsets {
+		result.ResultInfos[topicPartition] = kafka.ListOffsetsResultInfo{
+			Offset: kafka.Offset(m.latestOffsets[*topicPartition.Topic][topicPartition.Partition]),
+			Error:  kafka.NewError(kafka.ErrNoError, "", false),
+		}
+	}
+	return result, nil
+}
+
+func (m *KafkaAdminMockClient) DescribeCluster(ctx context.Context,
+	options ...kafka.DescribeClusterAdminOption) (result kafka.DescribeClusterResult, err error) {
+	if m.describeClusterErr != nil {
+		return result, m.describeClusterErr
+	}
+	result.Nodes = []kafka.Node{{ID: 0, Host: "kafka", Port: 9092}}
+	return result, nil
+}
+
+// SetDescribeClusterError sets the error that is returned by the DescribeCluster, e.g. the broker is unreachable.
+func (m *KafkaAdminMockClient) SetDescribeClusterError(err error) {
+	m.describeClusterErr = err
+}
+
+// SetConsumerGroup sets the number of the active members of the given consumer group.
+func (m *KafkaAdminMockClient) SetConsumerGroup(groupID string, members int) {
+	if _, ok := m.consumerGroups[groupID]; !ok {
+		m.consumerGroups[groupID] = &consumerGroup{offsets: map[string]map[int32]int64{}}
+	}
+	m.consumerGroups[groupID].members = members
+}
+
+// SetConsumerGroupOffset sets the committed offset of the given consumer group on the given topic partition.
+func (m *KafkaAdminMockClient) SetConsumerGroupOffset(groupID, topic string, partition int32, offset int64) {
+	if _, ok := m.consumerGroups[groupID]; !ok {
+		m.SetConsumerGroup(groupID, 0)
+	}
+	if _, ok := m.consumerGroups[groupID].offsets[topic]; !ok {
+		m.consumerGroups[groupID].offsets[topic] = map[int32]int64{}
+	}
+	m.consumerGroups[groupID].offsets[topic][partition] = offset
+}
+
+// SetLatestOffset sets the latest offset of the given topic partition.
+func (m *KafkaAdminMockClient) SetLatestOffset(topic string, partition int32, offset int64) {
+	if _, ok := m.latestOffsets[topic]; !ok {
+		m.latestOffsets[topic] = map[int32]int64{}
+	}
+	m.latestOffsets[topic][partition] = offset
+}
+
+// ClientQuotas returns the client quotas of the given user.
+func (m *KafkaAdminMockClient) ClientQuotas(user string) map[string]float64 {
+	return m.clientQuotas[user]
+}
+
+func (m *KafkaAdminMockClient) Close() {
+	m.closed = true
+}
+
+func (m *KafkaAdminMockClient) Closed() bool {
+	return m.closed
+}
+
+// DescribeACLsCalls returns the number of the DescribeACLs calls.
+func (m *KafkaAdminMockClient) DescribeACLsCalls() int {
+	return m.describeACLsCalls
+}
+
+// CreateACLsCalls returns the number of the CreateACLs calls.
+func (m *KafkaAdminMockClient) CreateACLsCalls() int {
+	return m.createACLsCalls
+}
+
+// DescribeConsumerGroupsCalls returns the number of the DescribeConsumerGroups calls.
+func (m *KafkaAdminMockClient) DescribeConsumerGroupsCalls() int {
+	return m.describeGroupsCalls
+}
+
+// ListConsumerGroupOffsetsCalls returns the number of the ListConsumerGroupOffsets calls.
+func (m *KafkaAdminMockClient) ListConsumerGroupOffsetsCalls() int {
+	return m.listGroupOffsetsCalls
+}
+
+// ListOffsetsCalls returns the number of the ListOffsets calls.
+func (m *KafkaAdminMockClient) ListOffsetsCalls() int {
+	return m.listOffsetsCalls
+}
+
 func (m *KafkaAdminMockClient) Topics() []string {
 	topics := []string{}
 	for _, topic := range m.topics.TopicDescriptions {
@@ -76,6 +370,15 @@ func (m *KafkaAdminMockClient) Topics() []string {
 	return topics
 }
 
+func (m *KafkaAdminMockClient) Partitions(topic string) int {
+	for _, t := range m.topics.TopicDescriptions {
+		if t.Name == topic {
+			return len(t.Partitions)
+		}
+	}
+	return 0
+}
+
 func (m *KafkaAdminMockClient) ACLs() []string {
 	acls := []string{}
 	for _, acl := range m.acls.ACLBindings {
@@ -83,3 +386,37 @@ func (m *KafkaAdminMockClient) ACLs() []string {
 	}
 	return acls
 }
+
+func matchACLBindingFilter(filter kafka.ACLBindingFilter, binding kafka.ACLBinding) bool {
+	if filter.Type != kafka.ResourceAny && filter.Type != binding.Type {
+		return false
+	}
+	if filter.Name != "" && filter.Name != binding.Name {
+		return false
+	}
+	if filter.ResourcePatternType != kafka.ResourcePatternTypeAny &&
+		filter.ResourcePatternType != binding.ResourcePatternType {
+		return false
+	}
+	if filter.Principal != "" && filter.Principal != binding.Principal {
+		return false
+	}
+	if filter.Host != "" && filter.Host != binding.Host {
+		return false
+	}
+	if filter.Operation != kafka.ACLOperationAny && filter.Operation != binding.Operation {
+		return false
+	}
+	if filter.PermissionType != kafka.ACLPermissionTypeAny && filter.PermissionType != binding.PermissionType {
+		return false
+	}
+	return true
+}
+
+func (m *KafkaAdminMockClient) ACLBindings() kafka.ACLBindings {
+	return m.acls.ACLBindings
+}
+
+func (m *KafkaAdminMockClient) ScramPassword(user string) string {
+	return m.scramCredentials[user].password
+}
@@ -24,6 +24,7 @@ func NewMaestroMockServer() *MaestroMockServer {
 
 				if strings.Contains(r.URL.RawQuery, Consumer) {
 					consumer := openapi.NewConsumer()
+					consumer.SetId(Consumer)
 					consumer.SetName(Consumer)
 					list.Items = []openapi.Consumer{*consumer}
 				}
@@ -36,6 +37,8 @@ func NewMaestroMockServer() *MaestroMockServer {
 			case http.MethodPost:
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusCreated)
+			case http.MethodDelete:
+				w.WriteHeader(http.StatusNoContent)
 			default:
 				w.WriteHeader(http.StatusNotImplemented)
 			}
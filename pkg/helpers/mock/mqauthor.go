@@ -3,7 +3,9 @@ package mock
 import "context"
 
 type MockMessageQueueAuthzCreator struct {
-	clusterName string
+	clusterName        string
+	deletedClusterName string
+	bulkClusterNames   []string
 }
 
 func NewMockMessageQueueAuthzCreator() *MockMessageQueueAuthzCreator {
@@ -16,9 +18,23 @@ func (a *MockMessageQueueAuthzCreator) CreateAuthorizations(ctx context.Context,
 }
 
 func (a *MockMessageQueueAuthzCreator) DeleteAuthorizations(ctx context.Context, clusterName string) error {
+	a.deletedClusterName = clusterName
 	return nil
 }
 
+func (a *MockMessageQueueAuthzCreator) CreateBulkAuthorizations(ctx context.Context, clusterNames []string) error {
+	a.bulkClusterNames = clusterNames
+	return nil
+}
+
+func (a *MockMessageQueueAuthzCreator) BulkClusterNames() []string {
+	return a.bulkClusterNames
+}
+
 func (a *MockMessageQueueAuthzCreator) ClusterName() string {
 	return a.clusterName
 }
+
+func (a *MockMessageQueueAuthzCreator) DeletedClusterName() string {
+	return a.deletedClusterName
+}
@@ -0,0 +1,116 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type MQTTDynSecACL struct {
+	ACLType string
+	Topic   string
+	Allow   bool
+}
+
+type mqttDynSecCommand struct {
+	Command  string `json:"command"`
+	Username string `json:"username"`
+	RoleName string `json:"rolename"`
+	ACLType  string `json:"acltype"`
+	Topic    string `json:"topic"`
+	Allow    bool   `json:"allow"`
+}
+
+type mqttDynSecResponse struct {
+	Command string `json:"command"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MQTTDynSecMockClient emulates the mosquitto dynamic security plugin with the in memory roles and clients.
+type MQTTDynSecMockClient struct {
+	roles   map[string][]MQTTDynSecACL
+	clients map[string][]string
+}
+
+func NewMQTTDynSecMockClient() *MQTTDynSecMockClient {
+	return &MQTTDynSecMockClient{
+		roles:   map[string][]MQTTDynSecACL{},
+		clients: map[string][]string{},
+	}
+}
+
+func (c *MQTTDynSecMockClient) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	request := map[string][]mqttDynSecCommand{}
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, err
+	}
+
+	responses := []mqttDynSecResponse{}
+	for _, command := range request["commands"] {
+		responses = append(responses, mqttDynSecResponse{Command: command.Command, Error: c.execute(command)})
+	}
+	return json.Marshal(map[string][]mqttDynSecResponse{"responses": responses})
+}
+
+func (c *MQTTDynSecMockClient) execute(command mqttDynSecCommand) string {
+	switch command.Command {
+	case "createRole":
+		if _, ok := c.roles[command.RoleName]; ok {
+			return "Role already exists"
+		}
+		c.roles[command.RoleName] = []MQTTDynSecACL{}
+	case "addRoleACL":
+		acls, ok := c.roles[command.RoleName]
+		if !ok {
+			return "Role not found"
+		}
+		for _, acl := range acls {
+			if acl.ACLType == command.ACLType && acl.Topic == command.Topic {
+				return "ACL with this topic already exists"
+			}
+		}
+		c.roles[command.RoleName] = append(acls, MQTTDynSecACL{
+			ACLType: command.ACLType, Topic: command.Topic, Allow: command.Allow})
+	case "deleteRole":
+		if _, ok := c.roles[command.RoleName]; !ok {
+			return "Role not found"
+		}
+		delete(c.roles, command.RoleName)
+	case "createClient":
+		if _, ok := c.clients[command.Username]; ok {
+			return "Client already exists"
+		}
+		c.clients[command.Username] = []string{}
+	case "addClientRole":
+		roles, ok := c.clients[command.Username]
+		if !ok {
+			return "Client not found"
+		}
+		if _, ok := c.roles[command.RoleName]; !ok {
+			return "Role not found"
+		}
+		for _, role := range roles {
+			if role == command.RoleName {
+				return ""
+			}
+		}
+		c.clients[command.Username] = append(roles, command.RoleName)
+	case "deleteClient":
+		if _, ok := c.clients[command.Username]; !ok {
+			return "Client not found"
+		}
+		delete(c.clients, command.Username)
+	default:
+		return fmt.Sprintf("Unknown command %s", command.Command)
+	}
+
+	return ""
+}
+
+func (c *MQTTDynSecMockClient) Roles() map[string][]MQTTDynSecACL {
+	return c.roles
+}
+
+func (c *MQTTDynSecMockClient) Clients() map[string][]string {
+	return c.clients
+}
@@ -0,0 +1,326 @@
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"k8s.io/apimachinery/pkg/util/errors"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+)
+
+const (
+	// mqttDynSecControlTopic is the topic of the mosquitto dynamic security plugin to receive the commands.
+	mqttDynSecControlTopic = "$CONTROL/dynamic-security/v1"
+	// mqttDynSecResponseTopic is the topic of the mosquitto dynamic security plugin to publish the command responses.
+	mqttDynSecResponseTopic = "$CONTROL/dynamic-security/v1/response"
+
+	mqttDynSecClientID = "maestro-addon-manager"
+)
+
+// MQTTDynSecCommand is a command of the mosquitto dynamic security plugin.
+type MQTTDynSecCommand struct {
+	Command  string `json:"command"`
+	Username string `json:"username,omitempty"`
+	RoleName string `json:"rolename,omitempty"`
+	ACLType  string `json:"acltype,omitempty"`
+	Topic    string `json:"topic,omitempty"`
+	Allow    bool   `json:"allow,omitempty"`
+}
+
+// MQTTDynSecACL is an ACL of a mosquitto dynamic security role.
+type MQTTDynSecACL struct {
+	ACLType string `json:"acltype"`
+	Topic   string `json:"topic"`
+	Allow   bool   `json:"allow"`
+}
+
+// MQTTDynSecResponse is the response of a mosquitto dynamic security command, the Error is empty if the command
+// is succeeded.
+type MQTTDynSecResponse struct {
+	Command string `json:"command"`
+	Error   string `json:"error,omitempty"`
+}
+
+// an interface for the mosquitto dynamic security plugin, this will help with testing
+type MQTTDynSecClient interface {
+	// Execute publishes the commands payload to the dynamic security control topic and returns the responses payload.
+	Execute(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// MQTTDialOptions are the options to connect to a MQTT broker.
+type MQTTDialOptions struct {
+	BrokerHost  string
+	Username    string
+	Password    string
+	TLSConfig   *tls.Config
+	DialTimeout time.Duration
+}
+
+// CreateMQTTACLs creates a dynamic security role with the ACLs of the agent of the given cluster and binds
+// the role to the agent client. The agent only can publish and subscribe the topics of its own cluster.
+func CreateMQTTACLs(ctx context.Context, client MQTTDynSecClient, sourceID, clusterName string) error {
+	roleName := toMQTTRoleName(clusterName)
+	username := ToAgentCommonName(clusterName)
+
+	// the role and client may already exist, so the ACLs and role are added separately to correct them
+	commands := []MQTTDynSecCommand{{Command: "createRole", RoleName: roleName}}
+	for _, acl := range mqttACLs(sourceID, clusterName) {
+		commands = append(commands, MQTTDynSecCommand{
+			Command:  "addRoleACL",
+			RoleName: roleName,
+			ACLType:  acl.ACLType,
+			Topic:    acl.Topic,
+			Allow:    acl.Allow,
+		})
+	}
+	commands = append(commands,
+		MQTTDynSecCommand{Command: "createClient", Username: username},
+		MQTTDynSecCommand{Command: "addClientRole", Username: username, RoleName: roleName},
+	)
+
+	return executeMQTTDynSecCommands(ctx, client, commands, isMQTTDynSecAlreadyExists)
+}
+
+// DeleteMQTTACLs deletes the dynamic security client and role of the agent of the given cluster.
+func DeleteMQTTACLs(ctx context.Context, client MQTTDynSecClient, clusterName string) error {
+	commands := []MQTTDynSecCommand{
+		{Command: "deleteClient", Username: ToAgentCommonName(clusterName)},
+		{Command: "deleteRole", RoleName: toMQTTRoleName(clusterName)},
+	}
+
+	return executeMQTTDynSecCommands(ctx, client, commands, isMQTTDynSecNotFound)
+}
+
+func executeMQTTDynSecCommands(ctx context.Context, client MQTTDynSecClient,
+	commands []MQTTDynSecCommand, ignored func(command, err string) bool) error {
+	logger := klog.FromContext(ctx)
+
+	payload, err := json.Marshal(map[string][]MQTTDynSecCommand{"commands": commands})
+	if err != nil {
+		return err
+	}
+
+	data, err := client.Execute(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	result := map[string][]MQTTDynSecResponse{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	responses := result["responses"]
+
+	if len(responses) != len(commands) {
+		return fmt.Errorf("expected %d dynamic security responses, but got %d", len(commands), len(responses))
+	}
+
+	errs := []error{}
+	for i, response := range responses {
+		if response.Error == "" {
+			continue
+		}
+
+		if ignored(commands[i].Command, response.Error) {
+			logger.V(4).Info(fmt.Sprintf("ignore the %s response: %s", commands[i].Command, response.Error))
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("failed to %s: %s", commands[i].Command, response.Error))
+	}
+
+	return errors.NewAggregate(errs)
+}
+
+// mqttACLs returns the ACLs of the agent, the agent subscribes the source events and publishes the agent events
+// on the topics that are prefixed with sources/<source>/consumers/<cluster>/.
+func mqttACLs(sourceID, clusterName string) []MQTTDynSecACL {
+	topic := ToMQTTClusterTopic(sourceID, clusterName)
+	return []MQTTDynSecACL{
+		{ACLType: "subscribePattern", Topic: topic, Allow: true},
+		{ACLType: "publishClientSend", Topic: topic, Allow: true},
+		{ACLType: "publishClientReceive", Topic: topic, Allow: true},
+	}
+}
+
+// ToMQTTClusterTopic returns the topic filter that covers the topics of the given cluster.
+func ToMQTTClusterTopic(sourceID, clusterName string) string {
+	return fmt.Sprintf("sources/%s/consumers/%s/#", sourceID, clusterName)
+}
+
+func toMQTTRoleName(clusterName string) string {
+	return fmt.Sprintf("%s-%s-agent", clusterName, common.AddOnName)
+}
+
+// the errors of the dynamic security commands that are ignored when the roles and clients are created or deleted,
+// they are the error field of the command responses of the mosquitto dynamic security plugin.
+var (
+	mqttDynSecAlreadyExistsErrors = map[string]string{
+		"createRole":   "Role already exists",
+		"addRoleACL":   "ACL with this topic already exists",
+		"createClient": "Client already exists",
+	}
+	mqttDynSecNotFoundErrors = map[string]string{
+		"deleteClient": "Client not found",
+		"deleteRole":   "Role not found",
+	}
+)
+
+func isMQTTDynSecAlreadyExists(command, err string) bool {
+	return mqttDynSecAlreadyExistsErrors[command] == err
+}
+
+func isMQTTDynSecNotFound(command, err string) bool {
+	return mqttDynSecNotFoundErrors[command] == err
+}
+
+type mosquittoDynSecClient struct {
+	sync.Mutex
+	options  MQTTDialOptions
+	clientID string
+
+	// the connection is shared by the executions, it is reconnected if it is closed
+	client *paho.Client
+	// the responses of the dynamic security plugin
+	received chan []byte
+}
+
+// NewMosquittoDynSecClient returns a client that sends the commands to the mosquitto dynamic security control topic,
+// it connects to the broker at the first execution and reuses the connection for the following executions. The
+// client ID has a random suffix, so the managers do not take over the connections of each other.
+func NewMosquittoDynSecClient(options MQTTDialOptions) MQTTDynSecClient {
+	return &mosquittoDynSecClient{
+		options:  options,
+		clientID: fmt.Sprintf("%s-%s", mqttDynSecClientID, utilrand.String(8)),
+		received: make(chan []byte, 1),
+	}
+}
+
+func (c *mosquittoDynSecClient) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	// the responses are published to a shared topic, so the executions are serialized
+	c.Lock()
+	defer c.Unlock()
+
+	client, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// drop the response of a previous execution that was timed out
+	select {
+	case <-c.received:
+	default:
+	}
+
+	if _, err := client.Publish(ctx, &paho.Publish{Topic: mqttDynSecControlTopic, QoS: 1, Payload: payload}); err != nil {
+		c.disconnect()
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(c.dialTimeout()):
+		return nil, fmt.Errorf("timeout waiting for the dynamic security responses")
+	case data := <-c.received:
+		return data, nil
+	}
+}
+
+// connect returns the connected client, it connects to the broker and subscribes the response topic if the client
+// is not connected yet or its connection is closed.
+func (c *mosquittoDynSecClient) connect(ctx context.Context) (*paho.Client, error) {
+	if c.client != nil {
+		select {
+		case <-c.client.Done():
+			c.client = nil
+		default:
+			return c.client, nil
+		}
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := paho.NewClient(paho.ClientConfig{
+		Conn: packets.NewThreadSafeConn(conn),
+		OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+			func(pr paho.PublishReceived) (bool, error) {
+				if pr.Packet.Topic != mqttDynSecResponseTopic {
+					return false, nil
+				}
+				select {
+				case c.received <- pr.Packet.Payload:
+				default:
+				}
+				return true, nil
+			},
+		},
+	})
+
+	connect := &paho.Connect{
+		ClientID:   c.clientID,
+		KeepAlive:  30,
+		CleanStart: true,
+	}
+	if c.options.Username != "" {
+		connect.Username = c.options.Username
+		connect.UsernameFlag = true
+		connect.Password = []byte(c.options.Password)
+		connect.PasswordFlag = true
+	}
+
+	if _, err := client.Connect(ctx, connect); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to connect to the MQTT broker %s: %v", c.options.BrokerHost, err)
+	}
+
+	if _, err := client.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: mqttDynSecResponseTopic, QoS: 1}},
+	}); err != nil {
+		_ = client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+		return nil, err
+	}
+
+	c.client = client
+	return client, nil
+}
+
+func (c *mosquittoDynSecClient) disconnect() {
+	if c.client == nil {
+		return
+	}
+
+	_ = c.client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+	c.client = nil
+}
+
+func (c *mosquittoDynSecClient) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.dialTimeout()}
+	if c.options.TLSConfig == nil {
+		return dialer.DialContext(ctx, "tcp", c.options.BrokerHost)
+	}
+
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: c.options.TLSConfig}
+	return tlsDialer.DialContext(ctx, "tcp", c.options.BrokerHost)
+}
+
+func (c *mosquittoDynSecClient) dialTimeout() time.Duration {
+	if c.options.DialTimeout == 0 {
+		return 60 * time.Second
+	}
+	return c.options.DialTimeout
+}
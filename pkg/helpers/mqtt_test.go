@@ -0,0 +1,124 @@
+package helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestCreateMQTTACLs(t *testing.T) {
+	roleName := toMQTTRoleName("cluster1")
+	username := ToAgentCommonName("cluster1")
+	expectedACLs := []mock.MQTTDynSecACL{
+		{ACLType: "subscribePattern", Topic: "sources/maestro/consumers/cluster1/#", Allow: true},
+		{ACLType: "publishClientSend", Topic: "sources/maestro/consumers/cluster1/#", Allow: true},
+		{ACLType: "publishClientReceive", Topic: "sources/maestro/consumers/cluster1/#", Allow: true},
+	}
+
+	cases := []struct {
+		name     string
+		existing bool
+	}{
+		{
+			name: "create acls",
+		},
+		{
+			name:     "acls already exist",
+			existing: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := mock.NewMQTTDynSecMockClient()
+			if c.existing {
+				if err := CreateMQTTACLs(context.Background(), client, "maestro", "cluster1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := CreateMQTTACLs(context.Background(), client, "maestro", "cluster1"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(client.Roles()[roleName], expectedACLs) {
+				t.Errorf("unexpected acls %v", client.Roles()[roleName])
+			}
+			if !reflect.DeepEqual(client.Clients()[username], []string{roleName}) {
+				t.Errorf("unexpected client roles %v", client.Clients()[username])
+			}
+		})
+	}
+}
+
+func TestDeleteMQTTACLs(t *testing.T) {
+	client := mock.NewMQTTDynSecMockClient()
+	for _, cluster := range []string{"cluster1", "cluster2"} {
+		if err := CreateMQTTACLs(context.Background(), client, "maestro", cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second deletion ensures the absent client and role are ignored
+	for i := 0; i < 2; i++ {
+		if err := DeleteMQTTACLs(context.Background(), client, "cluster1"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if _, ok := client.Roles()[toMQTTRoleName("cluster1")]; ok {
+		t.Errorf("expected the role of cluster1 is deleted")
+	}
+	if _, ok := client.Clients()[ToAgentCommonName("cluster1")]; ok {
+		t.Errorf("expected the client of cluster1 is deleted")
+	}
+	if _, ok := client.Roles()[toMQTTRoleName("cluster2")]; !ok {
+		t.Errorf("expected the role of cluster2 is kept")
+	}
+}
+
+func TestMQTTDynSecIgnoredErrors(t *testing.T) {
+	cases := []struct {
+		name            string
+		command         string
+		err             string
+		expectedExists  bool
+		expectedMissing bool
+	}{
+		{
+			name:           "role already exists",
+			command:        "createRole",
+			err:            "Role already exists",
+			expectedExists: true,
+		},
+		{
+			name:    "the role of a client is not found",
+			command: "addClientRole",
+			err:     "Role not found",
+		},
+		{
+			name:            "client not found",
+			command:         "deleteClient",
+			err:             "Client not found",
+			expectedMissing: true,
+		},
+		{
+			name:    "other error mentions not found",
+			command: "deleteRole",
+			err:     "Internal error: backing file not found",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if exists := isMQTTDynSecAlreadyExists(c.command, c.err); exists != c.expectedExists {
+				t.Errorf("expected already exists %v, but got %v", c.expectedExists, exists)
+			}
+			if missing := isMQTTDynSecNotFound(c.command, c.err); missing != c.expectedMissing {
+				t.Errorf("expected not found %v, but got %v", c.expectedMissing, missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+
+	kubeapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+	addonlisters "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	"open-cluster-management.io/sdk-go/pkg/patcher"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+)
+
+// updateAddOnConditions sets the given conditions to the maestro-addon ManagedClusterAddOn in the cluster namespace, the
+// status is only patched if the conditions are changed. It does nothing if the ManagedClusterAddOn does not exist.
+func updateAddOnConditions(ctx context.Context, addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	addOnLister addonlisters.ManagedClusterAddOnLister, clusterName string, conditions ...metav1.Condition) error {
+	return patchAddOnConditions(ctx, addOnClient, addOnLister, clusterName, func(existing *[]metav1.Condition) {
+		for _, condition := range conditions {
+			meta.SetStatusCondition(existing, condition)
+		}
+	})
+}
+
+// removeAddOnConditions removes the conditions of the given types from the maestro-addon ManagedClusterAddOn in the
+// cluster namespace. It does nothing if the ManagedClusterAddOn does not exist.
+func removeAddOnConditions(ctx context.Context, addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	addOnLister addonlisters.ManagedClusterAddOnLister, clusterName string, conditionTypes ...string) error {
+	return patchAddOnConditions(ctx, addOnClient, addOnLister, clusterName, func(existing *[]metav1.Condition) {
+		for _, conditionType := range conditionTypes {
+			meta.RemoveStatusCondition(existing, conditionType)
+		}
+	})
+}
+
+func patchAddOnConditions(ctx context.Context, addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	addOnLister addonlisters.ManagedClusterAddOnLister, clusterName string, mutate func(*[]metav1.Condition)) error {
+	addOn, err := addOnLister.ManagedClusterAddOns(clusterName).Get(common.AddOnName)
+	if kubeapierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	newAddOn := addOn.DeepCopy()
+	mutate(&newAddOn.Status.Conditions)
+
+	addOnPatcher := patcher.NewPatcher[
+		*addonv1alpha1.ManagedClusterAddOn, addonv1alpha1.ManagedClusterAddOnSpec, addonv1alpha1.ManagedClusterAddOnStatus](
+		addOnClient.ManagedClusterAddOns(clusterName))
+	_, err = addOnPatcher.PatchStatus(ctx, newAddOn, newAddOn.Status, addOn.Status)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"syscall"
 	"time"
 
@@ -12,44 +13,187 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	kubeapierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisters "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned/typed/cluster/v1"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterlisters "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/sdk-go/pkg/patcher"
 
+	"github.com/stolostron/maestro-addon/pkg/common"
 	"github.com/stolostron/maestro-addon/pkg/helpers"
 	"github.com/stolostron/maestro-addon/pkg/mq"
 )
 
+// messageQueueReadyInterval is the interval to check whether the message queue broker is bootstrapped.
+const messageQueueReadyInterval = 5 * time.Second
+
+const (
+	// ConsumerRegisteredCondition reports whether the consumer of the cluster is registered in the Maestro.
+	ConsumerRegisteredCondition = "ConsumerRegistered"
+	// MessageQueueAuthorizedCondition reports whether the message queue authorizations of the agent are created.
+	MessageQueueAuthorizedCondition = "MessageQueueAuthorized"
+)
+
 type ManagedClusterController struct {
+	clusterPatcher           patcher.Patcher[*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus]
 	clusterLister            clusterlisters.ManagedClusterLister
+	addOnLister              addonlisters.ManagedClusterAddOnLister
+	addOnClient              addonv1alpha1client.AddonV1alpha1Interface
 	maestroAPIClient         *openapi.APIClient
 	messageQueueAuthzCreator mq.MessageQueueAuthzCreator
 	rateLimiter              workqueue.RateLimiter
+
+	// bulkAuthz tracks the bulk pass of the authorizations, it is nil if the creator does not create the authorizations
+	// in bulk
+	bulkAuthz *bulkAuthorizations
+}
+
+// bulkAuthorizations tracks the bulk pass of the authorizations after the controller is started. The authorizations
+// of a cluster are not created by its reconciliation until the bulk pass is finished, and the first reconciliation of
+// a cluster that is authorized by the bulk pass skips the authorizations, so they are not created twice.
+type bulkAuthorizations struct {
+	sync.Mutex
+	finished   bool
+	authorized sets.Set[string]
+}
+
+func newBulkAuthorizations() *bulkAuthorizations {
+	return &bulkAuthorizations{authorized: sets.New[string]()}
+}
+
+// finish marks the bulk pass finished with the clusters that are authorized by it.
+func (b *bulkAuthorizations) finish(clusterNames ...string) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.finished = true
+	b.authorized.Insert(clusterNames...)
+}
+
+// skip returns an error if the bulk pass is not finished, or returns true if the given cluster is authorized by the
+// bulk pass and its authorizations are not skipped yet.
+func (b *bulkAuthorizations) skip(clusterName string) (bool, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.finished {
+		return false, fmt.Errorf("%w: the authorizations of the clusters are being created in bulk",
+			mq.ErrMessageQueueNotReady)
+	}
+
+	if !b.authorized.Has(clusterName) {
+		return false, nil
+	}
+
+	b.authorized.Delete(clusterName)
+	return true, nil
 }
 
 func NewManagedClusterController(maestroServiceAddress string,
+	clusterClient clusterv1client.ManagedClusterInterface,
 	clusterInformer clusterinformers.ManagedClusterInformer,
+	addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	addOnInformer addoninformers.ManagedClusterAddOnInformer,
 	messageQueueAuthzCreator mq.MessageQueueAuthzCreator,
+	watchdog *SyncWatchdog,
 	recorder events.Recorder) factory.Controller {
 	controller := &ManagedClusterController{
+		clusterPatcher: patcher.NewPatcher[
+			*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus](clusterClient),
 		clusterLister:            clusterInformer.Lister(),
+		addOnLister:              addOnInformer.Lister(),
+		addOnClient:              addOnClient,
 		maestroAPIClient:         helpers.NewMaestroAPIClient(maestroServiceAddress),
 		messageQueueAuthzCreator: messageQueueAuthzCreator,
 		rateLimiter:              workqueue.NewItemExponentialFailureRateLimiter(5*time.Second, 300*time.Second),
 	}
+	if _, ok := messageQueueAuthzCreator.(mq.BulkMessageQueueAuthzCreator); ok {
+		controller.bulkAuthz = newBulkAuthorizations()
+	}
 
 	return factory.New().
 		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, clusterInformer.Informer()).
-		WithSync(controller.sync).
+		// the cluster is reconciled when its maestro-addon is created, so the onboarding conditions are reported
+		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return accessor.GetNamespace()
+		}, factory.NamesFilter(common.AddOnName), addOnInformer.Informer()).
+		WithSync(watchdog.Wrap(controller.sync)).
+		WithPostStartHooks(controller.createBulkAuthorizations, watchdog.PostStartHook).
 		ToController("ManagedClusterController", recorder)
 }
 
+// createBulkAuthorizations creates the message queue authorizations of all onboarded clusters in one pass after the
+// controller is started, so the authorizations of a lot of clusters are created with less round trips when the
+// manager is restarted. The reconciliations of the clusters wait for this pass, and the failed authorizations are
+// created by the reconciliation of each cluster.
+func (c *ManagedClusterController) createBulkAuthorizations(ctx context.Context, controllerContext factory.SyncContext) error {
+	logger := klog.FromContext(ctx)
+
+	bulkCreator, ok := c.messageQueueAuthzCreator.(mq.BulkMessageQueueAuthzCreator)
+	if !ok || c.bulkAuthz == nil {
+		return nil
+	}
+
+	// the reconciliations of the clusters are not blocked if the pass is failed
+	authorized := []string{}
+	defer func() {
+		c.bulkAuthz.finish(authorized...)
+	}()
+
+	// the authorizations are created after the message queue broker is bootstrapped
+	if bootstrapper, ok := c.messageQueueAuthzCreator.(mq.Bootstrapper); ok {
+		if err := wait.PollUntilContextCancel(ctx, messageQueueReadyInterval, true,
+			func(ctx context.Context) (bool, error) {
+				return bootstrapper.Ready() == nil, nil
+			}); err != nil {
+			// the controller is stopped
+			return nil
+		}
+	}
+
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	clusterNames := []string{}
+	for _, managedCluster := range managedClusters {
+		if !managedCluster.DeletionTimestamp.IsZero() ||
+			!hasFinalizer(managedCluster, common.ManagedClusterCleanupFinalizer) {
+			continue
+		}
+
+		clusterNames = append(clusterNames, managedCluster.Name)
+	}
+
+	if len(clusterNames) == 0 {
+		return nil
+	}
+
+	if err := bulkCreator.CreateBulkAuthorizations(ctx, clusterNames); err != nil {
+		logger.Error(err, "Failed to create the message queue authorizations of the clusters in bulk")
+		return nil
+	}
+	authorized = clusterNames
+
+	logger.V(2).Info(fmt.Sprintf("The message queue authorizations of %d clusters are created", len(clusterNames)))
+	return nil
+}
+
 func (c *ManagedClusterController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
 	logger := klog.FromContext(ctx)
 
@@ -66,8 +210,7 @@ func (c *ManagedClusterController) sync(ctx context.Context, controllerContext f
 	}
 
 	if !managedCluster.DeletionTimestamp.IsZero() {
-		// TODO delete this cluster in the maestro
-		return nil
+		return c.cleanup(ctx, controllerContext, managedCluster)
 	}
 
 	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
@@ -75,21 +218,96 @@ func (c *ManagedClusterController) sync(ctx context.Context, controllerContext f
 		return nil
 	}
 
+	// add the finalizer before the consumer and authorizations are created, the following reconciliation
+	// will be triggered by the finalizer update.
+	updated, err := c.clusterPatcher.AddFinalizer(ctx, managedCluster, common.ManagedClusterCleanupFinalizer)
+	if updated || err != nil {
+		return err
+	}
+
 	if err := c.ensureConsumer(ctx, clusterName); err != nil {
+		if updateErr := updateAddOnConditions(ctx, c.addOnClient, c.addOnLister, clusterName,
+			toConsumerRegisteredCondition(clusterName, err)); updateErr != nil {
+			return updateErr
+		}
+
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			logger.V(2).Info(fmt.Sprintf("Requeue the cluster %s to wait the maestro service ready", clusterName))
+			connectionRefusedRequeues.WithLabelValues("ensure_consumer").Inc()
+			controllerContext.Queue().AddAfter(clusterName, c.rateLimiter.When(clusterName))
+			return nil
+		}
+
+		return err
+	}
+
+	// the consumer does not depend on the message queue broker, only the authorizations wait for the broker
+	aclErr := c.ensureACLs(ctx, controllerContext, managedCluster)
+	if err := updateAddOnConditions(ctx, c.addOnClient, c.addOnLister, clusterName,
+		toConsumerRegisteredCondition(clusterName, nil),
+		c.toMessageQueueAuthorizedCondition(aclErr)); err != nil {
+		return err
+	}
+
+	if aclErr != nil {
+		if errors.Is(aclErr, mq.ErrMessageQueueNotReady) {
+			logger.V(2).Info(fmt.Sprintf("Requeue the cluster %s to wait the message queue ready: %v", clusterName, aclErr))
+			controllerContext.Queue().AddAfter(clusterName, c.rateLimiter.When(clusterName))
+			return nil
+		}
+
+		return aclErr
+	}
+
+	return nil
+}
+
+// cleanup deletes the consumer of the cluster from the maestro and removes the message queue authorizations
+// of the cluster, the cleanup finalizer is removed from the cluster after both are deleted.
+func (c *ManagedClusterController) cleanup(ctx context.Context,
+	controllerContext factory.SyncContext, managedCluster *clusterv1.ManagedCluster) error {
+	logger := klog.FromContext(ctx)
+
+	clusterName := managedCluster.Name
+
+	if !hasFinalizer(managedCluster, common.ManagedClusterCleanupFinalizer) {
+		return nil
+	}
+
+	deleted, err := helpers.DeleteConsumer(ctx, c.maestroAPIClient, clusterName)
+	if err != nil {
 		if errors.Is(err, syscall.ECONNREFUSED) {
 			logger.V(2).Info(fmt.Sprintf("Requeue the cluster %s to wait the maestro service ready", clusterName))
+			connectionRefusedRequeues.WithLabelValues("delete_consumer").Inc()
 			controllerContext.Queue().AddAfter(clusterName, c.rateLimiter.When(clusterName))
 			return nil
 		}
 
+		controllerContext.Recorder().Warningf("ConsumerDeleteFailed",
+			"Failed to delete the maestro consumer of the cluster %s: %v", clusterName, err)
 		return err
 	}
+	if deleted {
+		controllerContext.Recorder().Eventf("ConsumerDeleted", "The maestro consumer of the cluster %s is deleted", clusterName)
+	}
 
-	return c.ensureACLs(ctx, clusterName)
+	if c.messageQueueAuthzCreator != nil {
+		if err := c.messageQueueAuthzCreator.DeleteAuthorizations(ctx, clusterName); err != nil {
+			controllerContext.Recorder().Warningf("AuthorizationsDeleteFailed",
+				"Failed to delete the message queue authorizations of the cluster %s: %v", clusterName, err)
+			return err
+		}
+		controllerContext.Recorder().Eventf("AuthorizationsDeleted",
+			"The message queue authorizations of the cluster %s are deleted", clusterName)
+	}
+
+	return c.clusterPatcher.RemoveFinalizer(ctx, managedCluster, common.ManagedClusterCleanupFinalizer)
 }
 
 func (c *ManagedClusterController) ensureConsumer(ctx context.Context, managedClusterName string) error {
+	start := time.Now()
 	existed, err := helpers.FindConsumerByName(ctx, c.maestroAPIClient, managedClusterName)
+	observeReconcileStep(reconcileStepConsumerLookup, start, err)
 	if err != nil {
 		return err
 	}
@@ -99,13 +317,114 @@ func (c *ManagedClusterController) ensureConsumer(ctx context.Context, managedCl
 	}
 
 	// create a consumer in the maestro
-	return helpers.CreateConsumer(ctx, c.maestroAPIClient, managedClusterName)
+	start = time.Now()
+	err = helpers.CreateConsumer(ctx, c.maestroAPIClient, managedClusterName)
+	observeReconcileStep(reconcileStepConsumerCreate, start, err)
+	return err
 }
 
-func (c *ManagedClusterController) ensureACLs(ctx context.Context, managedClusterName string) error {
-	if c.messageQueueAuthzCreator != nil {
-		return c.messageQueueAuthzCreator.CreateAuthorizations(ctx, managedClusterName)
+func (c *ManagedClusterController) ensureACLs(ctx context.Context,
+	controllerContext factory.SyncContext, managedCluster *clusterv1.ManagedCluster) (err error) {
+	if c.messageQueueAuthzCreator == nil {
+		return nil
 	}
 
-	return nil
+	if c.bulkAuthz != nil {
+		skipped, err := c.bulkAuthz.skip(managedCluster.Name)
+		if err != nil || skipped {
+			return err
+		}
+	}
+
+	defer func(start time.Time) {
+		observeReconcileStep(reconcileStepACLEnsure, start, err)
+	}(time.Now())
+
+	quotaCreator, ok := c.messageQueueAuthzCreator.(mq.QuotaMessageQueueAuthzCreator)
+	if !ok {
+		return c.messageQueueAuthzCreator.CreateAuthorizations(ctx, managedCluster.Name)
+	}
+
+	// the client quotas of the annotation override the default client quotas, the default client quotas are
+	// used if the annotation is invalid
+	overrides, err := helpers.ParseKafkaClientQuotas(managedCluster.Annotations[common.KafkaClientQuotasAnnotation])
+	if err != nil {
+		controllerContext.Recorder().Warningf("InvalidClientQuotas",
+			"The client quotas annotation of the cluster %s is ignored: %v", managedCluster.Name, err)
+		overrides = helpers.KafkaClientQuotas{}
+	}
+
+	return quotaCreator.CreateAuthorizationsWithQuotas(ctx, managedCluster.Name, overrides)
+}
+
+// toConsumerRegisteredCondition returns the ConsumerRegistered condition of the result of the consumer registration.
+func toConsumerRegisteredCondition(clusterName string, err error) metav1.Condition {
+	switch {
+	case err == nil:
+		return metav1.Condition{
+			Type:    ConsumerRegisteredCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ConsumerRegistered",
+			Message: fmt.Sprintf("The consumer %s is registered in the Maestro", clusterName),
+		}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return metav1.Condition{
+			Type:    ConsumerRegisteredCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MaestroUnavailable",
+			Message: fmt.Sprintf("The Maestro API is unavailable, the consumer %s will be registered later: %v", clusterName, err),
+		}
+	default:
+		return metav1.Condition{
+			Type:    ConsumerRegisteredCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ConsumerRegistrationFailed",
+			Message: fmt.Sprintf("Failed to register the consumer %s in the Maestro: %v", clusterName, err),
+		}
+	}
+}
+
+// toMessageQueueAuthorizedCondition returns the MessageQueueAuthorized condition of the result of the message queue
+// authorizations.
+func (c *ManagedClusterController) toMessageQueueAuthorizedCondition(err error) metav1.Condition {
+	switch {
+	case c.messageQueueAuthzCreator == nil:
+		return metav1.Condition{
+			Type:    MessageQueueAuthorizedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "AuthorizationsNotManaged",
+			Message: "The message queue authorizations of the agent are managed out of the maestro-addon",
+		}
+	case err == nil:
+		return metav1.Condition{
+			Type:    MessageQueueAuthorizedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Authorized",
+			Message: "The message queue authorizations of the agent are created",
+		}
+	case errors.Is(err, mq.ErrMessageQueueNotReady):
+		return metav1.Condition{
+			Type:    MessageQueueAuthorizedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "MessageQueueNotReady",
+			Message: fmt.Sprintf("The message queue authorizations of the agent will be created later: %v", err),
+		}
+	default:
+		return metav1.Condition{
+			Type:    MessageQueueAuthorizedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AuthorizationFailed",
+			Message: fmt.Sprintf("Failed to create the message queue authorizations of the agent: %v", err),
+		}
+	}
+}
+
+func hasFinalizer(managedCluster *clusterv1.ManagedCluster, finalizer string) bool {
+	for _, f := range managedCluster.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+
+	return false
 }
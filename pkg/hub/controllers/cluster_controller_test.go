@@ -2,15 +2,29 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics/testutil"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	fakeaddonclient "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	addonlisters "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/sdk-go/pkg/patcher"
 
+	"github.com/stolostron/maestro-addon/pkg/common"
 	"github.com/stolostron/maestro-addon/pkg/helpers"
 	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
 	"github.com/stolostron/maestro-addon/pkg/mq"
@@ -29,10 +43,13 @@ func TestClusterSync(t *testing.T) {
 		clusters                  []runtime.Object
 		authz                     mq.MessageQueueAuthzCreator
 		expectedAuthorizedCluster string
+		expectedDeletedCluster    string
+		validateActions           func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
-			name:     "cluster not found",
-			clusters: []runtime.Object{},
+			name:            "cluster not found",
+			clusters:        []runtime.Object{},
+			validateActions: noAction,
 		},
 		{
 			name: "cluster is deleting",
@@ -42,6 +59,25 @@ func TestClusterSync(t *testing.T) {
 					DeletionTimestamp: &now,
 				},
 			}},
+			validateActions: noAction,
+		},
+		{
+			name: "cluster is deleting with the cleanup finalizer",
+			clusters: []runtime.Object{&clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              clusterName,
+					DeletionTimestamp: &now,
+					Finalizers:        []string{common.ManagedClusterCleanupFinalizer},
+				},
+			}},
+			authz:                  mock.NewMockMessageQueueAuthzCreator(),
+			expectedDeletedCluster: clusterName,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				cluster := patchedCluster(t, actions)
+				if len(cluster.Finalizers) != 0 {
+					t.Errorf("expected finalizer is removed, but got %v", cluster.Finalizers)
+				}
+			},
 		},
 		{
 			name: "cluster is not joined",
@@ -50,9 +86,10 @@ func TestClusterSync(t *testing.T) {
 					Name: clusterName,
 				},
 			}},
+			validateActions: noAction,
 		},
 		{
-			name: "a joined cluster (no authz)",
+			name: "a joined cluster without the cleanup finalizer",
 			clusters: []runtime.Object{&clusterv1.ManagedCluster{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: clusterName,
@@ -66,12 +103,38 @@ func TestClusterSync(t *testing.T) {
 					},
 				},
 			}},
+			authz: mock.NewMockMessageQueueAuthzCreator(),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				cluster := patchedCluster(t, actions)
+				if len(cluster.Finalizers) != 1 || cluster.Finalizers[0] != common.ManagedClusterCleanupFinalizer {
+					t.Errorf("expected finalizer is added, but got %v", cluster.Finalizers)
+				}
+			},
+		},
+		{
+			name: "a joined cluster (no authz)",
+			clusters: []runtime.Object{&clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       clusterName,
+					Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   clusterv1.ManagedClusterConditionJoined,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
+			}},
+			validateActions: noAction,
 		},
 		{
 			name: "a joined cluster",
 			clusters: []runtime.Object{&clusterv1.ManagedCluster{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: clusterName,
+					Name:       clusterName,
+					Finalizers: []string{common.ManagedClusterCleanupFinalizer},
 				},
 				Status: clusterv1.ManagedClusterStatus{
 					Conditions: []metav1.Condition{
@@ -84,6 +147,7 @@ func TestClusterSync(t *testing.T) {
 			}},
 			authz:                     mock.NewMockMessageQueueAuthzCreator(),
 			expectedAuthorizedCluster: clusterName,
+			validateActions:           noAction,
 		},
 	}
 
@@ -98,8 +162,14 @@ func TestClusterSync(t *testing.T) {
 				}
 			}
 
+			addOnClient, addOnLister := newAddOnClientAndLister(t)
 			ctrl := &ManagedClusterController{
+				clusterPatcher: patcher.NewPatcher[
+					*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus](
+					clusterClient.ClusterV1().ManagedClusters()),
 				clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnClient:              addOnClient.AddonV1alpha1(),
+				addOnLister:              addOnLister,
 				maestroAPIClient:         helpers.NewMaestroAPIClient(maestroServer.URL()),
 				messageQueueAuthzCreator: c.authz,
 			}
@@ -112,8 +182,509 @@ func TestClusterSync(t *testing.T) {
 				if c.expectedAuthorizedCluster != authorizedCluster {
 					t.Errorf("unexpected authz for : %s", authorizedCluster)
 				}
+
+				deletedCluster := c.authz.(*mock.MockMessageQueueAuthzCreator).DeletedClusterName()
+				if c.expectedDeletedCluster != deletedCluster {
+					t.Errorf("unexpected authz deletion for : %s", deletedCluster)
+				}
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
+
+// notReadyAuthzCreator fails the authorizations until the message queue broker is bootstrapped.
+type notReadyAuthzCreator struct {
+	*mock.MockMessageQueueAuthzCreator
+}
+
+func (a *notReadyAuthzCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
+	return mq.ErrMessageQueueNotReady
+}
+
+func TestClusterSyncMessageQueueNotReady(t *testing.T) {
+	clusterName := "cluster1"
+	maestroServer := mock.NewMaestroMockServer()
+
+	maestroServer.Start()
+	defer maestroServer.Stop()
+
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       clusterName,
+			Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:   clusterv1.ManagedClusterConditionJoined,
+					Status: metav1.ConditionTrue,
+				},
+			},
+		},
+	}
+
+	clusterClient := fakeclusterclient.NewSimpleClientset(cluster)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	addOnClient, addOnLister := newAddOnClientAndLister(t)
+	ctrl := &ManagedClusterController{
+		clusterPatcher: patcher.NewPatcher[
+			*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus](
+			clusterClient.ClusterV1().ManagedClusters()),
+		clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		addOnClient:              addOnClient.AddonV1alpha1(),
+		addOnLister:              addOnLister,
+		maestroAPIClient:         helpers.NewMaestroAPIClient(maestroServer.URL()),
+		messageQueueAuthzCreator: &notReadyAuthzCreator{MockMessageQueueAuthzCreator: mock.NewMockMessageQueueAuthzCreator()},
+		rateLimiter:              workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second),
+	}
+
+	lookups := reconcileStepDuration.WithLabelValues(reconcileStepConsumerLookup, "success")
+	aclRequeues := reconcileStepDuration.WithLabelValues(reconcileStepACLEnsure, "requeue")
+	lookupCount, _ := testutil.GetHistogramMetricCount(lookups)
+	aclRequeueCount, _ := testutil.GetHistogramMetricCount(aclRequeues)
+
+	syncContext := mock.NewMockSyncContext(t, clusterName)
+	if err := ctrl.sync(context.Background(), syncContext); err != nil {
+		t.Errorf("expected the cluster is requeued without error, but got %v", err)
+	}
+
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true,
+		func(ctx context.Context) (bool, error) {
+			return syncContext.Queue().Len() == 1, nil
+		}); err != nil {
+		t.Errorf("expected the cluster is requeued")
+	}
+
+	if count, _ := testutil.GetHistogramMetricCount(lookups); count != lookupCount+1 {
+		t.Errorf("expected the consumer lookup is recorded, but got %d", count-lookupCount)
+	}
+	if count, _ := testutil.GetHistogramMetricCount(aclRequeues); count != aclRequeueCount+1 {
+		t.Errorf("expected the acl ensure requeue is recorded, but got %d", count-aclRequeueCount)
+	}
+}
+
+// quotaAuthzCreator records the client quotas overrides of the authorizations.
+type quotaAuthzCreator struct {
+	*mock.MockMessageQueueAuthzCreator
+	overrides *helpers.KafkaClientQuotas
+}
+
+func (a *quotaAuthzCreator) CreateAuthorizationsWithQuotas(ctx context.Context,
+	clusterName string, overrides helpers.KafkaClientQuotas) error {
+	a.overrides = &overrides
+	return a.CreateAuthorizations(ctx, clusterName)
+}
+
+func TestClusterSyncWithClientQuotas(t *testing.T) {
+	clusterName := "cluster1"
+	maestroServer := mock.NewMaestroMockServer()
+
+	maestroServer.Start()
+	defer maestroServer.Stop()
+
+	producerByteRate := int64(1048576)
+
+	cases := []struct {
+		name              string
+		annotations       map[string]string
+		expectedOverrides helpers.KafkaClientQuotas
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:              "client quotas annotation",
+			annotations:       map[string]string{common.KafkaClientQuotasAnnotation: "producerByteRate=1048576"},
+			expectedOverrides: helpers.KafkaClientQuotas{ProducerByteRate: &producerByteRate},
+		},
+		{
+			name:        "invalid client quotas annotation",
+			annotations: map[string]string{common.KafkaClientQuotasAnnotation: "producerByteRate=-1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        clusterName,
+					Annotations: c.annotations,
+					Finalizers:  []string{common.ManagedClusterCleanupFinalizer},
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   clusterv1.ManagedClusterConditionJoined,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
+			}
+
+			clusterClient := fakeclusterclient.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			authz := &quotaAuthzCreator{MockMessageQueueAuthzCreator: mock.NewMockMessageQueueAuthzCreator()}
+			addOnClient, addOnLister := newAddOnClientAndLister(t)
+			ctrl := &ManagedClusterController{
+				clusterPatcher: patcher.NewPatcher[
+					*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus](
+					clusterClient.ClusterV1().ManagedClusters()),
+				clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnClient:              addOnClient.AddonV1alpha1(),
+				addOnLister:              addOnLister,
+				maestroAPIClient:         helpers.NewMaestroAPIClient(maestroServer.URL()),
+				messageQueueAuthzCreator: authz,
+			}
+			if err := ctrl.sync(context.Background(), mock.NewMockSyncContext(t, clusterName)); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			if authz.overrides == nil {
+				t.Fatalf("expected the authorizations are created with quotas")
+			}
+			if !reflect.DeepEqual(*authz.overrides, c.expectedOverrides) {
+				t.Errorf("expected overrides %v, but got %v", c.expectedOverrides, *authz.overrides)
+			}
+		})
+	}
+}
+
+func TestCreateBulkAuthorizations(t *testing.T) {
+	now := metav1.Now()
+	clusters := []runtime.Object{
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "cluster1",
+				Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+			},
+		},
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "cluster2",
+				DeletionTimestamp: &now,
+				Finalizers:        []string{common.ManagedClusterCleanupFinalizer},
+			},
+		},
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster3",
+			},
+		},
+	}
+
+	clusterClient := fakeclusterclient.NewSimpleClientset(clusters...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
+	for _, cluster := range clusters {
+		if err := clusterStore.Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	authz := mock.NewMockMessageQueueAuthzCreator()
+	ctrl := &ManagedClusterController{
+		clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		messageQueueAuthzCreator: authz,
+		bulkAuthz:                newBulkAuthorizations(),
+	}
+	if err := ctrl.createBulkAuthorizations(context.Background(), mock.NewMockSyncContext(t, "")); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	// only the onboarded clusters are authorized in bulk
+	if !reflect.DeepEqual(authz.BulkClusterNames(), []string{"cluster1"}) {
+		t.Errorf("unexpected bulk authorized clusters %v", authz.BulkClusterNames())
+	}
+}
+
+var kafkaACLOptions = helpers.KafkaACLOptions{
+	Profile:             helpers.KafkaACLProfileRestricted,
+	AgentAuthentication: helpers.KafkaAgentAuthenticationCertificate,
+}
+
+// kafkaACLAuthzCreator creates the ACLs of the agents with the Kafka admin client, so the admin calls of the bulk
+// pass and the reconciliations can be counted.
+type kafkaACLAuthzCreator struct {
+	*mock.MockMessageQueueAuthzCreator
+	adminClient *mock.KafkaAdminMockClient
+}
+
+func (a *kafkaACLAuthzCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
+	return helpers.CreateACLs(ctx, a.adminClient, kafkaACLOptions, clusterName)
+}
+
+func (a *kafkaACLAuthzCreator) CreateBulkAuthorizations(ctx context.Context, clusterNames []string) error {
+	return helpers.CreateBulkACLs(ctx, a.adminClient, kafkaACLOptions, clusterNames)
+}
+
+func TestBulkAuthorizationsAdminCalls(t *testing.T) {
+	maestroServer := mock.NewMaestroMockServer()
+
+	maestroServer.Start()
+	defer maestroServer.Stop()
+
+	clusters := []runtime.Object{}
+	for _, clusterName := range []string{"cluster1", "cluster2"} {
+		clusters = append(clusters, &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       clusterName,
+				Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+			},
+			Status: clusterv1.ManagedClusterStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   clusterv1.ManagedClusterConditionJoined,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		})
+	}
+
+	clusterClient := fakeclusterclient.NewSimpleClientset(clusters...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+	for _, cluster := range clusters {
+		if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addOnClient, addOnLister := newAddOnClientAndLister(t)
+	adminClient := mock.NewKafkaAdminMockClient()
+	ctrl := &ManagedClusterController{
+		clusterPatcher: patcher.NewPatcher[
+			*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus](
+			clusterClient.ClusterV1().ManagedClusters()),
+		clusterLister:    clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		addOnClient:      addOnClient.AddonV1alpha1(),
+		addOnLister:      addOnLister,
+		maestroAPIClient: helpers.NewMaestroAPIClient(maestroServer.URL()),
+		messageQueueAuthzCreator: &kafkaACLAuthzCreator{
+			MockMessageQueueAuthzCreator: mock.NewMockMessageQueueAuthzCreator(),
+			adminClient:                  adminClient,
+		},
+		rateLimiter: workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second),
+		bulkAuthz:   newBulkAuthorizations(),
+	}
+
+	syncClusters := func(clusterNames ...string) {
+		for _, clusterName := range clusterNames {
+			if err := ctrl.sync(context.Background(), mock.NewMockSyncContext(t, clusterName)); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+		}
+	}
+	expectAdminCalls := func(step string, describeACLs, createACLs int) {
+		if adminClient.DescribeACLsCalls() != describeACLs || adminClient.CreateACLsCalls() != createACLs {
+			t.Errorf("%s: expected %d DescribeACLs and %d CreateACLs calls, but got %d and %d", step,
+				describeACLs, createACLs, adminClient.DescribeACLsCalls(), adminClient.CreateACLsCalls())
+		}
+	}
+
+	// the reconciliations wait for the bulk pass
+	syncClusters("cluster1", "cluster2")
+	expectAdminCalls("before the bulk pass", 0, 0)
+
+	// the existing ACLs are described for each principal, and the missing ACLs are created with one call
+	if err := ctrl.createBulkAuthorizations(context.Background(), mock.NewMockSyncContext(t, "")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	expectAdminCalls("the bulk pass", 2, 1)
+
+	// the clusters that are authorized by the bulk pass are not authorized again
+	syncClusters("cluster1", "cluster2")
+	expectAdminCalls("after the bulk pass", 2, 1)
+
+	// the following reconciliations correct the ACLs of each cluster
+	syncClusters("cluster1")
+	expectAdminCalls("the resync", 3, 1)
+}
+
+func noAction(t *testing.T, actions []clienttesting.Action) {
+	if len(actions) != 0 {
+		t.Errorf("expected no action, but got %v", actions)
+	}
+}
+
+func patchedCluster(t *testing.T, actions []clienttesting.Action) *clusterv1.ManagedCluster {
+	if len(actions) != 1 || actions[0].GetVerb() != "patch" {
+		t.Fatalf("expected one patch action, but got %v", actions)
+	}
+
+	cluster := &clusterv1.ManagedCluster{}
+	patch := actions[0].(clienttesting.PatchActionImpl).GetPatch()
+	if err := json.Unmarshal(patch, cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	return cluster
+}
+
+func newAddOnClientAndLister(t *testing.T,
+	addOns ...*addonv1alpha1.ManagedClusterAddOn) (*fakeaddonclient.Clientset, addonlisters.ManagedClusterAddOnLister) {
+	objects := []runtime.Object{}
+	for _, addOn := range addOns {
+		objects = append(objects, addOn)
+	}
+
+	addOnClient := fakeaddonclient.NewSimpleClientset(objects...)
+	addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+	for _, addOn := range addOns {
+		if err := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return addOnClient, addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister()
+}
+
+// failingAuthzCreator fails the authorizations with the given error.
+type failingAuthzCreator struct {
+	*mock.MockMessageQueueAuthzCreator
+	err error
+}
+
+func (a *failingAuthzCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
+	return a.err
+}
+
+func TestClusterSyncAddOnConditions(t *testing.T) {
+	clusterName := "cluster1"
+	maestroServer := mock.NewMaestroMockServer()
+
+	maestroServer.Start()
+	defer maestroServer.Stop()
+
+	cases := []struct {
+		name               string
+		maestroServerURL   string
+		authz              mq.MessageQueueAuthzCreator
+		expectedErr        bool
+		expectedConditions []metav1.Condition
+	}{
+		{
+			name:             "the cluster is onboarded",
+			maestroServerURL: maestroServer.URL(),
+			authz:            mock.NewMockMessageQueueAuthzCreator(),
+			expectedConditions: []metav1.Condition{
+				{Type: ConsumerRegisteredCondition, Status: metav1.ConditionTrue, Reason: "ConsumerRegistered"},
+				{Type: MessageQueueAuthorizedCondition, Status: metav1.ConditionTrue, Reason: "Authorized"},
+			},
+		},
+		{
+			name:             "the authorizations are not managed",
+			maestroServerURL: maestroServer.URL(),
+			expectedConditions: []metav1.Condition{
+				{Type: ConsumerRegisteredCondition, Status: metav1.ConditionTrue, Reason: "ConsumerRegistered"},
+				{Type: MessageQueueAuthorizedCondition, Status: metav1.ConditionTrue, Reason: "AuthorizationsNotManaged"},
+			},
+		},
+		{
+			name:             "the maestro is unavailable",
+			maestroServerURL: "http://127.0.0.1:1",
+			authz:            mock.NewMockMessageQueueAuthzCreator(),
+			expectedConditions: []metav1.Condition{
+				{Type: ConsumerRegisteredCondition, Status: metav1.ConditionFalse, Reason: "MaestroUnavailable"},
+			},
+		},
+		{
+			name:             "the message queue is not ready",
+			maestroServerURL: maestroServer.URL(),
+			authz:            &notReadyAuthzCreator{MockMessageQueueAuthzCreator: mock.NewMockMessageQueueAuthzCreator()},
+			expectedConditions: []metav1.Condition{
+				{Type: ConsumerRegisteredCondition, Status: metav1.ConditionTrue, Reason: "ConsumerRegistered"},
+				{Type: MessageQueueAuthorizedCondition, Status: metav1.ConditionFalse, Reason: "MessageQueueNotReady"},
+			},
+		},
+		{
+			name:             "the authorizations are failed",
+			maestroServerURL: maestroServer.URL(),
+			authz: &failingAuthzCreator{
+				MockMessageQueueAuthzCreator: mock.NewMockMessageQueueAuthzCreator(),
+				err:                          errors.New("access denied"),
+			},
+			expectedErr: true,
+			expectedConditions: []metav1.Condition{
+				{Type: ConsumerRegisteredCondition, Status: metav1.ConditionTrue, Reason: "ConsumerRegistered"},
+				{Type: MessageQueueAuthorizedCondition, Status: metav1.ConditionFalse, Reason: "AuthorizationFailed"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       clusterName,
+					Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   clusterv1.ManagedClusterConditionJoined,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
 			}
 
+			clusterClient := fakeclusterclient.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			addOnClient, addOnLister := newAddOnClientAndLister(t, &addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{Name: common.AddOnName, Namespace: clusterName},
+			})
+
+			ctrl := &ManagedClusterController{
+				clusterPatcher: patcher.NewPatcher[
+					*clusterv1.ManagedCluster, clusterv1.ManagedClusterSpec, clusterv1.ManagedClusterStatus](
+					clusterClient.ClusterV1().ManagedClusters()),
+				clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnClient:              addOnClient.AddonV1alpha1(),
+				addOnLister:              addOnLister,
+				maestroAPIClient:         helpers.NewMaestroAPIClient(c.maestroServerURL),
+				messageQueueAuthzCreator: c.authz,
+				rateLimiter:              workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second),
+			}
+
+			err := ctrl.sync(context.Background(), mock.NewMockSyncContext(t, clusterName))
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+
+			actions := addOnClient.Actions()
+			if len(actions) != 1 || actions[0].GetVerb() != "patch" {
+				t.Fatalf("expected one patch action, but got %v", actions)
+			}
+			patched := &addonv1alpha1.ManagedClusterAddOn{}
+			if err := json.Unmarshal(actions[0].(clienttesting.PatchActionImpl).GetPatch(), patched); err != nil {
+				t.Fatal(err)
+			}
+			if len(patched.Status.Conditions) != len(c.expectedConditions) {
+				t.Fatalf("expected conditions %v, but got %v", c.expectedConditions, patched.Status.Conditions)
+			}
+			for _, expected := range c.expectedConditions {
+				condition := meta.FindStatusCondition(patched.Status.Conditions, expected.Type)
+				if condition == nil || condition.Status != expected.Status || condition.Reason != expected.Reason {
+					t.Errorf("expected condition %v, but got %v", expected, condition)
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	kubeapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisters "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisters "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+	"github.com/stolostron/maestro-addon/pkg/mq"
+)
+
+const (
+	// MessageQueueConnectedCondition reports whether the agent of a cluster is connected to the message queue broker,
+	// it is derived from the active members of the consumer group of the agent.
+	MessageQueueConnectedCondition = "MessageQueueConnected"
+
+	agentConnectedReason    = "AgentConnected"
+	agentDisconnectedReason = "AgentDisconnected"
+)
+
+// AgentConnectivityController checks the consumer groups of the agents of the onboarded clusters periodically, the
+// agent of a cluster is connected if its consumer group has active members. The result is set to the
+// MessageQueueConnected condition of the maestro-addon ManagedClusterAddOn of the cluster, so the disconnected agents
+// can be found on the hub.
+type AgentConnectivityController struct {
+	clusterLister  clusterlisters.ManagedClusterLister
+	addOnLister    addonlisters.ManagedClusterAddOnLister
+	addOnClient    addonv1alpha1client.AddonV1alpha1Interface
+	groupDescriber mq.ConsumerGroupDescriber
+	clock          clock.Clock
+	// the timeout of describing the consumer groups of all clusters in a sync
+	timeout time.Duration
+
+	// the last time that the consumer group of the agent of each cluster had active members
+	lastSeen map[string]time.Time
+}
+
+func NewAgentConnectivityController(clusterInformer clusterinformers.ManagedClusterInformer,
+	addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	addOnInformer addoninformers.ManagedClusterAddOnInformer,
+	groupDescriber mq.ConsumerGroupDescriber,
+	interval time.Duration,
+	recorder events.Recorder) factory.Controller {
+	controller := &AgentConnectivityController{
+		clusterLister:  clusterInformer.Lister(),
+		addOnLister:    addOnInformer.Lister(),
+		addOnClient:    addOnClient,
+		groupDescriber: groupDescriber,
+		clock:          clock.RealClock{},
+		timeout:        interval,
+		lastSeen:       map[string]time.Time{},
+	}
+
+	return factory.New().
+		WithBareInformers(clusterInformer.Informer(), addOnInformer.Informer()).
+		WithSync(controller.sync).
+		ResyncEvery(interval).
+		ToController("AgentConnectivityController", recorder)
+}
+
+func (c *AgentConnectivityController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	logger := klog.FromContext(ctx)
+
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	clusterNames := sets.New[string]()
+	for _, managedCluster := range managedClusters {
+		if !managedCluster.DeletionTimestamp.IsZero() ||
+			!hasFinalizer(managedCluster, common.ManagedClusterCleanupFinalizer) {
+			continue
+		}
+
+		clusterNames.Insert(managedCluster.Name)
+	}
+
+	errs := []error{}
+
+	// only the members of the consumer groups are described, the consumer groups of all clusters are described at
+	// once and the describe is bounded by the resync interval
+	describeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	statuses, err := c.groupDescriber.DescribeAgentConsumerGroups(describeCtx, sets.List(clusterNames))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to describe the consumer groups of the clusters: %v", err))
+	}
+
+	for _, clusterName := range sets.List(clusterNames) {
+		// the condition is kept if the consumer group cannot be described, e.g. the broker is unreachable, because
+		// it does not tell whether the agent is connected
+		status, ok := statuses[clusterName]
+		if !ok {
+			continue
+		}
+
+		addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(common.AddOnName)
+		if kubeapierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		logger.V(4).Info(fmt.Sprintf("The consumer group %s of the cluster %s has %d active members",
+			status.GroupID, clusterName, status.Members))
+
+		existing := meta.FindStatusCondition(addOn.Status.Conditions, MessageQueueConnectedCondition)
+		if err := updateAddOnConditions(ctx, c.addOnClient, c.addOnLister, clusterName,
+			c.toConnectedCondition(clusterName, status, existing)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// forget the removed clusters
+	for clusterName := range c.lastSeen {
+		if !clusterNames.Has(clusterName) {
+			delete(c.lastSeen, clusterName)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// toConnectedCondition returns the MessageQueueConnected condition of the given consumer group status. The last-seen
+// time of a disconnected agent is recorded in the condition message when it disconnects, and the existing message is
+// kept afterward, so the last-seen time is not lost after the manager is restarted.
+func (c *AgentConnectivityController) toConnectedCondition(clusterName string, status helpers.KafkaConsumerGroupStatus,
+	existing *metav1.Condition) metav1.Condition {
+	now := c.clock.Now()
+
+	if status.Members > 0 {
+		c.lastSeen[clusterName] = now
+		return metav1.Condition{
+			Type:    MessageQueueConnectedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  agentConnectedReason,
+			Message: fmt.Sprintf("The consumer group %s of the agent has %d active members", status.GroupID, status.Members),
+		}
+	}
+
+	if existing != nil && existing.Status == metav1.ConditionFalse && existing.Reason == agentDisconnectedReason {
+		return *existing
+	}
+
+	lastSeen, ok := c.lastSeen[clusterName]
+	if !ok && existing != nil && existing.Status == metav1.ConditionTrue {
+		// the agent was connected at the previous check before the manager is restarted
+		lastSeen, ok = now, true
+	}
+
+	message := fmt.Sprintf("The consumer group %s of the agent has no active members, the agent has never been seen",
+		status.GroupID)
+	if ok {
+		message = fmt.Sprintf("The consumer group %s of the agent has no active members, the agent was last seen at %s",
+			status.GroupID, lastSeen.UTC().Format(time.RFC3339))
+	}
+
+	return metav1.Condition{
+		Type:    MessageQueueConnectedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  agentDisconnectedReason,
+		Message: message,
+	}
+}
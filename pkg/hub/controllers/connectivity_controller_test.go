@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	fakeaddonclient "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestAgentConnectivitySync(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name              string
+		members           int
+		lastSeen          map[string]time.Time
+		existing          []metav1.Condition
+		expectedStatus    metav1.ConditionStatus
+		expectedReason    string
+		expectedMessage   string
+		expectedNoActions bool
+	}{
+		{
+			name:            "the agent is connected",
+			members:         1,
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  agentConnectedReason,
+			expectedMessage: "has 1 active members",
+		},
+		{
+			name:            "the agent has never been seen",
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  agentDisconnectedReason,
+			expectedMessage: "the agent has never been seen",
+		},
+		{
+			name:            "the agent is disconnected",
+			lastSeen:        map[string]time.Time{"cluster1": now.Add(-time.Minute)},
+			existing:        []metav1.Condition{{Type: MessageQueueConnectedCondition, Status: metav1.ConditionTrue}},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  agentDisconnectedReason,
+			expectedMessage: "the agent was last seen at 2023-12-31T23:59:00Z",
+		},
+		{
+			name:            "the agent is disconnected after the manager is restarted",
+			existing:        []metav1.Condition{{Type: MessageQueueConnectedCondition, Status: metav1.ConditionTrue}},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  agentDisconnectedReason,
+			expectedMessage: "the agent was last seen at 2024-01-01T00:00:00Z",
+		},
+		{
+			name: "the last-seen time of the disconnected agent is kept",
+			existing: []metav1.Condition{{
+				Type:    MessageQueueConnectedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  agentDisconnectedReason,
+				Message: "the agent was last seen at 2023-12-31T00:00:00Z",
+			}},
+			expectedNoActions: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "cluster1",
+					Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+				},
+			}
+			addOn := &addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{Name: common.AddOnName, Namespace: "cluster1"},
+				Status:     addonv1alpha1.ManagedClusterAddOnStatus{Conditions: c.existing},
+			}
+
+			clusterClient := fakeclusterclient.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			addOnClient := fakeaddonclient.NewSimpleClientset(addOn)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			if err := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn); err != nil {
+				t.Fatal(err)
+			}
+
+			lastSeen := c.lastSeen
+			if lastSeen == nil {
+				lastSeen = map[string]time.Time{}
+			}
+			lastSeen["cluster2"] = now
+
+			ctrl := &AgentConnectivityController{
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnLister:   addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				addOnClient:   addOnClient.AddonV1alpha1(),
+				groupDescriber: fakeConsumerGroupDescriber{
+					"cluster1": helpers.KafkaConsumerGroupStatus{GroupID: "cluster1-work-agent", Members: c.members},
+				},
+				clock:    testingclock.NewFakeClock(now),
+				timeout:  time.Minute,
+				lastSeen: lastSeen,
+			}
+			if err := ctrl.sync(context.Background(), mock.NewMockSyncContext(t, "key")); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			if _, ok := ctrl.lastSeen["cluster2"]; ok {
+				t.Errorf("expected the removed cluster is forgotten")
+			}
+
+			actions := addOnClient.Actions()
+			if c.expectedNoActions {
+				if len(actions) != 0 {
+					t.Errorf("expected no actions, but got %v", actions)
+				}
+				return
+			}
+
+			if len(actions) != 1 || actions[0].GetVerb() != "patch" {
+				t.Fatalf("expected one patch action, but got %v", actions)
+			}
+			patched := &addonv1alpha1.ManagedClusterAddOn{}
+			if err := json.Unmarshal(actions[0].(clienttesting.PatchActionImpl).GetPatch(), patched); err != nil {
+				t.Fatal(err)
+			}
+			condition := meta.FindStatusCondition(patched.Status.Conditions, MessageQueueConnectedCondition)
+			if condition == nil || condition.Status != c.expectedStatus || condition.Reason != c.expectedReason ||
+				!strings.Contains(condition.Message, c.expectedMessage) {
+				t.Errorf("expected condition %s %s %q, but got %v", c.expectedStatus, c.expectedReason, c.expectedMessage, condition)
+			}
+		})
+	}
+}
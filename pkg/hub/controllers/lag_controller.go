@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	kubeapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	addonlisters "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisters "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+	"github.com/stolostron/maestro-addon/pkg/mq"
+)
+
+const (
+	consumerGroupLagExceededReason = "ConsumerGroupLagExceeded"
+	consumerGroupLagNormalReason   = "ConsumerGroupLagNormal"
+)
+
+// ConsumerGroupLagController describes the consumer groups of the agents of the onboarded clusters periodically, the
+// lag of each consumer group on the source events topic is exposed as a metric. If the lag exceeds the threshold, the
+// maestro-addon ManagedClusterAddOn of the cluster is marked as degraded.
+type ConsumerGroupLagController struct {
+	clusterLister  clusterlisters.ManagedClusterLister
+	addOnLister    addonlisters.ManagedClusterAddOnLister
+	addOnClient    addonv1alpha1client.AddonV1alpha1Interface
+	groupDescriber mq.ConsumerGroupDescriber
+	lagThreshold   int64
+	// the timeout of describing the consumer groups of all clusters in a sync
+	timeout time.Duration
+
+	// the clusters whose lag is reported, the metrics of the removed clusters are deleted
+	reportedClusters sets.Set[string]
+}
+
+func NewConsumerGroupLagController(clusterInformer clusterinformers.ManagedClusterInformer,
+	addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	addOnInformer addoninformers.ManagedClusterAddOnInformer,
+	groupDescriber mq.ConsumerGroupDescriber,
+	lagThreshold int64,
+	interval time.Duration,
+	recorder events.Recorder) factory.Controller {
+	controller := &ConsumerGroupLagController{
+		clusterLister:    clusterInformer.Lister(),
+		addOnLister:      addOnInformer.Lister(),
+		addOnClient:      addOnClient,
+		groupDescriber:   groupDescriber,
+		lagThreshold:     lagThreshold,
+		timeout:          interval,
+		reportedClusters: sets.New[string](),
+	}
+
+	return factory.New().
+		WithBareInformers(clusterInformer.Informer(), addOnInformer.Informer()).
+		WithSync(controller.sync).
+		ResyncEvery(interval).
+		ToController("ConsumerGroupLagController", recorder)
+}
+
+func (c *ConsumerGroupLagController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	logger := klog.FromContext(ctx)
+
+	managedClusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	clusterNames := sets.New[string]()
+	for _, managedCluster := range managedClusters {
+		if !managedCluster.DeletionTimestamp.IsZero() ||
+			!hasFinalizer(managedCluster, common.ManagedClusterCleanupFinalizer) {
+			continue
+		}
+
+		clusterNames.Insert(managedCluster.Name)
+	}
+
+	errs := []error{}
+
+	// the consumer groups of all clusters are described at once and the describe is bounded by the resync interval,
+	// so an unreachable broker does not hold the worker across the passes
+	describeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	statuses, err := c.groupDescriber.DescribeAgentConsumerGroupsLag(describeCtx, sets.List(clusterNames))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to describe the consumer groups of the clusters: %v", err))
+	}
+
+	for _, clusterName := range sets.List(clusterNames) {
+		if c.lagThreshold <= 0 {
+			// the lag is not monitored, the Degraded condition that was set with a threshold is cleared
+			if err := c.clearDegradedCondition(ctx, clusterName); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		status, ok := statuses[clusterName]
+		if !ok {
+			continue
+		}
+
+		logger.V(4).Info(fmt.Sprintf("The consumer group %s of the cluster %s is %s with %d members, the lag is %d",
+			status.GroupID, clusterName, status.State, status.Members, status.Lag))
+		agentConsumerGroupLag.WithLabelValues(clusterName).Set(float64(status.Lag))
+
+		if c.lagThreshold <= 0 {
+			continue
+		}
+
+		if err := updateAddOnConditions(ctx, c.addOnClient, c.addOnLister, clusterName,
+			c.toDegradedCondition(status.GroupID, status.Lag)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, clusterName := range sets.List(c.reportedClusters.Difference(clusterNames)) {
+		agentConsumerGroupLag.DeleteLabelValues(clusterName)
+	}
+	c.reportedClusters = clusterNames
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// clearDegradedCondition removes the Degraded condition of the maestro-addon ManagedClusterAddOn of the given cluster if
+// it is set by this controller, the Degraded conditions that are set by the others are kept.
+func (c *ConsumerGroupLagController) clearDegradedCondition(ctx context.Context, clusterName string) error {
+	addOn, err := c.addOnLister.ManagedClusterAddOns(clusterName).Get(common.AddOnName)
+	if kubeapierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	condition := meta.FindStatusCondition(addOn.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionDegraded)
+	if condition == nil ||
+		(condition.Reason != consumerGroupLagExceededReason && condition.Reason != consumerGroupLagNormalReason) {
+		return nil
+	}
+
+	return removeAddOnConditions(ctx, c.addOnClient, c.addOnLister, clusterName,
+		addonv1alpha1.ManagedClusterAddOnConditionDegraded)
+}
+
+func (c *ConsumerGroupLagController) toDegradedCondition(groupID string, lag int64) metav1.Condition {
+	if lag > c.lagThreshold {
+		return metav1.Condition{
+			Type:   addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+			Status: metav1.ConditionTrue,
+			Reason: consumerGroupLagExceededReason,
+			Message: fmt.Sprintf("The lag %d of the consumer group %s exceeds the threshold %d",
+				lag, groupID, c.lagThreshold),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  consumerGroupLagNormalReason,
+		Message: fmt.Sprintf("The lag of the consumer group %s is within the threshold %d", groupID, c.lagThreshold),
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/testutil"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	fakeaddonclient "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+// fakeConsumerGroupDescriber returns the consumer group status of the clusters.
+type fakeConsumerGroupDescriber map[string]helpers.KafkaConsumerGroupStatus
+
+func (d fakeConsumerGroupDescriber) DescribeAgentConsumerGroups(ctx context.Context,
+	clusterNames []string) (map[string]helpers.KafkaConsumerGroupStatus, error) {
+	statuses := map[string]helpers.KafkaConsumerGroupStatus{}
+	for _, clusterName := range clusterNames {
+		if status, ok := d[clusterName]; ok {
+			status.Lag = 0
+			statuses[clusterName] = status
+		}
+	}
+	return statuses, nil
+}
+
+func (d fakeConsumerGroupDescriber) DescribeAgentConsumerGroupsLag(ctx context.Context,
+	clusterNames []string) (map[string]helpers.KafkaConsumerGroupStatus, error) {
+	statuses := map[string]helpers.KafkaConsumerGroupStatus{}
+	for _, clusterName := range clusterNames {
+		if status, ok := d[clusterName]; ok {
+			statuses[clusterName] = status
+		}
+	}
+	return statuses, nil
+}
+
+func TestConsumerGroupLagSync(t *testing.T) {
+	cases := []struct {
+		name              string
+		lag               int64
+		threshold         int64
+		existingCondition *metav1.Condition
+		expectedCondition *metav1.Condition
+		expectedRemoved   bool
+	}{
+		{
+			name: "the threshold is not set",
+			lag:  100,
+		},
+		{
+			name: "the threshold is unset after the lag exceeded it",
+			lag:  100,
+			existingCondition: &metav1.Condition{
+				Type:   addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+				Status: metav1.ConditionTrue,
+				Reason: "ConsumerGroupLagExceeded",
+			},
+			expectedRemoved: true,
+		},
+		{
+			name: "the threshold is not set and the addon is degraded by the others",
+			lag:  100,
+			existingCondition: &metav1.Condition{
+				Type:   addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+				Status: metav1.ConditionTrue,
+				Reason: "AgentUnhealthy",
+			},
+		},
+		{
+			name:      "the lag is within the threshold",
+			lag:       10,
+			threshold: 50,
+			expectedCondition: &metav1.Condition{
+				Type:   addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+				Status: metav1.ConditionFalse,
+				Reason: "ConsumerGroupLagNormal",
+			},
+		},
+		{
+			name:      "the lag exceeds the threshold",
+			lag:       100,
+			threshold: 50,
+			expectedCondition: &metav1.Condition{
+				Type:   addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+				Status: metav1.ConditionTrue,
+				Reason: "ConsumerGroupLagExceeded",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "cluster1",
+					Finalizers: []string{common.ManagedClusterCleanupFinalizer},
+				},
+			}
+			addOn := &addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{Name: common.AddOnName, Namespace: "cluster1"},
+			}
+			if c.existingCondition != nil {
+				addOn.Status.Conditions = []metav1.Condition{*c.existingCondition}
+			}
+
+			clusterClient := fakeclusterclient.NewSimpleClientset(cluster)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
+			if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+				t.Fatal(err)
+			}
+
+			addOnClient := fakeaddonclient.NewSimpleClientset(addOn)
+			addOnInformerFactory := addoninformers.NewSharedInformerFactory(addOnClient, time.Minute*10)
+			if err := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn); err != nil {
+				t.Fatal(err)
+			}
+
+			ctrl := &ConsumerGroupLagController{
+				clusterLister: clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				addOnLister:   addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				addOnClient:   addOnClient.AddonV1alpha1(),
+				groupDescriber: fakeConsumerGroupDescriber{
+					"cluster1": {GroupID: "cluster1-work-agent", Lag: c.lag},
+				},
+				lagThreshold:     c.threshold,
+				timeout:          time.Minute,
+				reportedClusters: sets.New[string]("cluster2"),
+			}
+			if err := ctrl.sync(context.Background(), mock.NewMockSyncContext(t, "key")); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			lag, err := testutil.GetGaugeMetricValue(agentConsumerGroupLag.WithLabelValues("cluster1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lag != float64(c.lag) {
+				t.Errorf("expected lag %d, but got %v", c.lag, lag)
+			}
+			if ctrl.reportedClusters.Has("cluster2") {
+				t.Errorf("expected the removed cluster is not reported")
+			}
+
+			actions := addOnClient.Actions()
+			if c.expectedRemoved {
+				if len(actions) != 1 || actions[0].GetVerb() != "patch" {
+					t.Fatalf("expected one patch action, but got %v", actions)
+				}
+				patched, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns("cluster1").Get(
+					context.Background(), common.AddOnName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if condition := meta.FindStatusCondition(patched.Status.Conditions,
+					addonv1alpha1.ManagedClusterAddOnConditionDegraded); condition != nil {
+					t.Errorf("expected the degraded condition is removed, but got %v", condition)
+				}
+				return
+			}
+			if c.expectedCondition == nil {
+				if len(actions) != 0 {
+					t.Errorf("expected no actions, but got %v", actions)
+				}
+				return
+			}
+
+			if len(actions) != 1 || actions[0].GetVerb() != "patch" {
+				t.Fatalf("expected one patch action, but got %v", actions)
+			}
+			patched := &addonv1alpha1.ManagedClusterAddOn{}
+			if err := json.Unmarshal(actions[0].(clienttesting.PatchActionImpl).GetPatch(), patched); err != nil {
+				t.Fatal(err)
+			}
+			condition := meta.FindStatusCondition(patched.Status.Conditions, c.expectedCondition.Type)
+			if condition == nil || condition.Status != c.expectedCondition.Status || condition.Reason != c.expectedCondition.Reason {
+				t.Errorf("expected condition %v, but got %v", c.expectedCondition, condition)
+			}
+		})
+	}
+}
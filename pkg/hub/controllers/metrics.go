@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// register the workqueue metrics, e.g. the workqueue_depth of the ManagedClusterController queue
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+
+	"github.com/stolostron/maestro-addon/pkg/mq"
+)
+
+// the steps of the reconciliation of a cluster
+const (
+	reconcileStepConsumerLookup = "consumer_lookup"
+	reconcileStepConsumerCreate = "consumer_create"
+	reconcileStepACLEnsure      = "acl_ensure"
+)
+
+var agentConsumerGroupLag = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Name: "maestro_addon_agent_consumer_group_lag",
+		Help: "The number of the source events that are not consumed by the consumer group of the agent of a cluster.",
+	},
+	[]string{"cluster"},
+)
+
+var reconcileStepDuration = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Name:    "maestro_addon_reconcile_step_duration_seconds",
+		Help:    "The duration of the steps of the ManagedCluster reconciliation by the step and the result.",
+		Buckets: metrics.DefBuckets,
+	},
+	[]string{"step", "result"},
+)
+
+var connectionRefusedRequeues = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Name: "maestro_addon_connection_refused_requeues_total",
+		Help: "The number of the ManagedCluster requeues because the connection to the Maestro API is refused.",
+	},
+	[]string{"operation"},
+)
+
+func init() {
+	legacyregistry.MustRegister(agentConsumerGroupLag)
+	legacyregistry.MustRegister(reconcileStepDuration)
+	legacyregistry.MustRegister(connectionRefusedRequeues)
+}
+
+// observeReconcileStep records the duration and the result of a reconciliation step, the result is success, requeue
+// if the step waits for the Maestro API or the message queue broker, or error.
+func observeReconcileStep(step string, start time.Time, err error) {
+	result := "success"
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, mq.ErrMessageQueueNotReady):
+		result = "requeue"
+	case err != nil:
+		result = "error"
+	}
+	reconcileStepDuration.WithLabelValues(step, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/utils/clock"
+)
+
+// SyncWatchdog tracks the syncs of a controller to detect a stuck worker queue, the worker is stuck if a sync does not
+// return within the timeout, or the queue has keys but no sync is started within the timeout.
+type SyncWatchdog struct {
+	sync.Mutex
+	timeout time.Duration
+	clock   clock.Clock
+
+	// the queue of the controller, it is recorded when the controller is started
+	queue interface{ Len() int }
+	// the start times of the in-flight syncs by their queue keys, a key is not synced by two workers at the same time
+	inFlight map[string]time.Time
+	// the last time that a sync is started or returned
+	lastActive time.Time
+	// the first time that the queue is found non-empty after it is empty, the queue is observed by the Stuck, so an
+	// idle queue that receives keys is not stuck until no sync is started within the timeout after the keys are found
+	nonEmptySince time.Time
+}
+
+// NewSyncWatchdog returns a watchdog that reports the worker queue as stuck if it does not make progress within the
+// given timeout.
+func NewSyncWatchdog(timeout time.Duration) *SyncWatchdog {
+	return newSyncWatchdog(timeout, clock.RealClock{})
+}
+
+func newSyncWatchdog(timeout time.Duration, clock clock.Clock) *SyncWatchdog {
+	return &SyncWatchdog{
+		timeout:    timeout,
+		clock:      clock,
+		inFlight:   map[string]time.Time{},
+		lastActive: clock.Now(),
+	}
+}
+
+// PostStartHook records the queue of the controller, it is registered as a post start hook of the controller.
+func (w *SyncWatchdog) PostStartHook(ctx context.Context, controllerContext factory.SyncContext) error {
+	w.Lock()
+	defer w.Unlock()
+
+	w.queue = controllerContext.Queue()
+	w.lastActive = w.clock.Now()
+	return nil
+}
+
+// Wrap returns a sync func that records the start and the return of each sync of the given sync func.
+func (w *SyncWatchdog) Wrap(syncFunc factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, controllerContext factory.SyncContext) error {
+		key := controllerContext.QueueKey()
+		w.started(key)
+		defer w.returned(key)
+
+		return syncFunc(ctx, controllerContext)
+	}
+}
+
+// Stuck returns an error if a sync is running longer than the timeout, or the queue has keys but no sync is started
+// within the timeout.
+func (w *SyncWatchdog) Stuck() error {
+	w.Lock()
+	defer w.Unlock()
+
+	now := w.clock.Now()
+	for key, startTime := range w.inFlight {
+		if elapsed := now.Sub(startTime); elapsed > w.timeout {
+			return fmt.Errorf("the sync of %q has been running for %s", key, elapsed.Round(time.Second))
+		}
+	}
+
+	if len(w.inFlight) > 0 || w.queue == nil {
+		return nil
+	}
+
+	queued := w.queue.Len()
+	if queued == 0 {
+		w.nonEmptySince = time.Time{}
+		return nil
+	}
+	if w.nonEmptySince.IsZero() {
+		w.nonEmptySince = now
+	}
+
+	// the keys may be queued long after the last sync, so the queue is only stuck if no sync is started within the
+	// timeout after both the last sync and the keys are found
+	since := w.lastActive
+	if w.nonEmptySince.After(since) {
+		since = w.nonEmptySince
+	}
+	if elapsed := now.Sub(since); elapsed > w.timeout {
+		return fmt.Errorf("%d keys are queued, but no sync is started for %s", queued, elapsed.Round(time.Second))
+	}
+
+	return nil
+}
+
+func (w *SyncWatchdog) started(key string) {
+	w.Lock()
+	defer w.Unlock()
+
+	now := w.clock.Now()
+	w.inFlight[key] = now
+	w.lastActive = now
+}
+
+func (w *SyncWatchdog) returned(key string) {
+	w.Lock()
+	defer w.Unlock()
+
+	delete(w.inFlight, key)
+	w.lastActive = w.clock.Now()
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+func TestSyncWatchdog(t *testing.T) {
+	cases := []struct {
+		name        string
+		idle        time.Duration
+		queued      []string
+		inFlight    bool
+		elapsed     time.Duration
+		expectStuck bool
+	}{
+		{
+			name: "the queue is idle",
+		},
+		{
+			name:    "the queue is idle for a long time",
+			elapsed: 10 * time.Minute,
+		},
+		{
+			name:    "the keys are queued",
+			queued:  []string{"cluster1"},
+			elapsed: time.Minute,
+		},
+		{
+			name:        "the keys are queued, but no sync is started",
+			queued:      []string{"cluster1"},
+			elapsed:     10 * time.Minute,
+			expectStuck: true,
+		},
+		{
+			name:    "the keys are queued after the queue is idle for a long time",
+			idle:    10 * time.Minute,
+			queued:  []string{"cluster1"},
+			elapsed: time.Minute,
+		},
+		{
+			name:        "the keys are queued after the queue is idle for a long time, but no sync is started",
+			idle:        10 * time.Minute,
+			queued:      []string{"cluster1"},
+			elapsed:     10 * time.Minute,
+			expectStuck: true,
+		},
+		{
+			name:     "the sync is running",
+			inFlight: true,
+			elapsed:  time.Minute,
+		},
+		{
+			name:        "the sync does not return",
+			inFlight:    true,
+			elapsed:     10 * time.Minute,
+			expectStuck: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			watchdog := newSyncWatchdog(5*time.Minute, fakeClock)
+
+			syncCtx := mock.NewMockSyncContext(t, "cluster2")
+			if err := watchdog.PostStartHook(context.Background(), syncCtx); err != nil {
+				t.Fatal(err)
+			}
+
+			// run a sync, and block it in the sync func if it is in flight
+			returned := make(chan struct{})
+			synced := make(chan struct{})
+			go func() {
+				_ = watchdog.Wrap(func(ctx context.Context, controllerContext factory.SyncContext) error {
+					close(synced)
+					if c.inFlight {
+						<-returned
+					}
+					return nil
+				})(context.Background(), syncCtx)
+			}()
+			<-synced
+			if !c.inFlight {
+				waitForReturned(t, watchdog)
+			}
+
+			fakeClock.Step(c.idle)
+			for _, key := range c.queued {
+				syncCtx.Queue().Add(key)
+			}
+			// the keys are just queued, the watchdog finds them with the first probe
+			if err := watchdog.Stuck(); err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+			fakeClock.Step(c.elapsed)
+
+			err := watchdog.Stuck()
+			close(returned)
+			if c.expectStuck && err == nil {
+				t.Errorf("expected the queue is stuck")
+			}
+			if !c.expectStuck && err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+		})
+	}
+}
+
+func waitForReturned(t *testing.T, watchdog *SyncWatchdog) {
+	for i := 0; i < 100; i++ {
+		watchdog.Lock()
+		inFlight := len(watchdog.inFlight)
+		watchdog.Unlock()
+		if inFlight == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the sync is not returned")
+}
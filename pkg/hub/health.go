@@ -0,0 +1,107 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift-online/maestro/pkg/api/openapi"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+	"github.com/stolostron/maestro-addon/pkg/hub/controllers"
+	"github.com/stolostron/maestro-addon/pkg/mq"
+)
+
+// healthProbeTimeout is the timeout of the checks that call the Maestro API and the message queue broker.
+const healthProbeTimeout = 5 * time.Second
+
+// messageQueueReadyzCheck reports whether the message queue broker is bootstrapped and reachable, the creators that do
+// not hold a connection to the broker are ready once they are bootstrapped.
+func messageQueueReadyzCheck(creator mq.MessageQueueAuthzCreator) healthz.HealthChecker {
+	return healthz.NamedCheck("message-queue", func(r *http.Request) error {
+		if bootstrapper, ok := creator.(mq.Bootstrapper); ok {
+			if err := bootstrapper.Ready(); err != nil {
+				return err
+			}
+		}
+
+		checker, ok := creator.(mq.ConnectivityChecker)
+		if !ok {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+		defer cancel()
+		return checker.CheckConnectivity(ctx)
+	})
+}
+
+// maestroAPIReadyzCheck reports whether the Maestro API is reachable.
+func maestroAPIReadyzCheck(client *openapi.APIClient) healthz.HealthChecker {
+	return healthz.NamedCheck("maestro-api", func(r *http.Request) error {
+		ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+		defer cancel()
+		return helpers.PingMaestroAPI(ctx, client)
+	})
+}
+
+// informerSyncReadyzCheck reports whether the given informers are synced.
+func informerSyncReadyzCheck(informers map[string]cache.SharedIndexInformer) healthz.HealthChecker {
+	return healthz.NamedCheck("informer-sync", func(_ *http.Request) error {
+		unsynced := []string{}
+		for name, informer := range informers {
+			if !informer.HasSynced() {
+				unsynced = append(unsynced, name)
+			}
+		}
+		if len(unsynced) == 0 {
+			return nil
+		}
+
+		sort.Strings(unsynced)
+		return fmt.Errorf("the informers %s are not synced", strings.Join(unsynced, ", "))
+	})
+}
+
+// workerQueueLivezCheck reports whether the worker queue of the controller is stuck.
+func workerQueueLivezCheck(watchdog *controllers.SyncWatchdog) healthz.HealthChecker {
+	return healthz.NamedCheck("worker-queue", func(_ *http.Request) error {
+		return watchdog.Stuck()
+	})
+}
+
+// serveHealthProbes serves the /readyz and /livez endpoints with the given checks on the given address until the
+// context is done, the result of each check is shown with the ?verbose query, and each check can be requested with
+// its own path, e.g. /readyz/maestro-api.
+func serveHealthProbes(ctx context.Context, address string, readyzChecks, livezChecks []healthz.HealthChecker) {
+	logger := klog.FromContext(ctx)
+
+	mux := http.NewServeMux()
+	healthz.InstallReadyzHandler(mux, append([]healthz.HealthChecker{healthz.PingHealthz}, readyzChecks...)...)
+	healthz.InstallLivezHandler(mux, append([]healthz.HealthChecker{healthz.PingHealthz}, livezChecks...)...)
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: healthProbeTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := server.Close(); err != nil {
+			logger.Error(err, "Failed to close the health probe server")
+		}
+	}()
+
+	logger.Info(fmt.Sprintf("serving the health probes on %s", address))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err, "Failed to serve the health probes")
+	}
+}
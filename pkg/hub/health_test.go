@@ -0,0 +1,96 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/stolostron/maestro-addon/pkg/mq"
+)
+
+type fakeMessageQueueCreator struct {
+	readyErr        error
+	connectivityErr error
+}
+
+func (c *fakeMessageQueueCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
+	return nil
+}
+
+func (c *fakeMessageQueueCreator) DeleteAuthorizations(ctx context.Context, clusterName string) error {
+	return nil
+}
+
+func (c *fakeMessageQueueCreator) Bootstrap(ctx context.Context) {}
+
+func (c *fakeMessageQueueCreator) Ready() error {
+	return c.readyErr
+}
+
+func (c *fakeMessageQueueCreator) CheckConnectivity(ctx context.Context) error {
+	return c.connectivityErr
+}
+
+type fakeInformer struct {
+	cache.SharedIndexInformer
+	synced bool
+}
+
+func (i *fakeInformer) HasSynced() bool {
+	return i.synced
+}
+
+func TestMessageQueueReadyzCheck(t *testing.T) {
+	cases := []struct {
+		name        string
+		creator     mq.MessageQueueAuthzCreator
+		expectedErr string
+	}{
+		{
+			name: "the message queue authorizations are disabled",
+		},
+		{
+			name:    "the broker is ready",
+			creator: &fakeMessageQueueCreator{},
+		},
+		{
+			name:        "the broker is not bootstrapped",
+			creator:     &fakeMessageQueueCreator{readyErr: mq.ErrMessageQueueNotReady},
+			expectedErr: mq.ErrMessageQueueNotReady.Error(),
+		},
+		{
+			name:        "the broker is unreachable",
+			creator:     &fakeMessageQueueCreator{connectivityErr: errors.New("connection refused")},
+			expectedErr: "connection refused",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := messageQueueReadyzCheck(c.creator).Check(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if len(c.expectedErr) == 0 && err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+			if len(c.expectedErr) != 0 && (err == nil || !strings.Contains(err.Error(), c.expectedErr)) {
+				t.Errorf("expected err %q, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestInformerSyncReadyzCheck(t *testing.T) {
+	check := informerSyncReadyzCheck(map[string]cache.SharedIndexInformer{
+		"managedclusters":      &fakeInformer{synced: true},
+		"managedclusteraddons": &fakeInformer{},
+	})
+
+	err := check.Check(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if err == nil || err.Error() != "the informers managedclusteraddons are not synced" {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
@@ -2,13 +2,25 @@ package hub
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/spf13/pflag"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	addonclientset "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 
+	"github.com/stolostron/maestro-addon/pkg/helpers"
 	"github.com/stolostron/maestro-addon/pkg/hub/controllers"
 	"github.com/stolostron/maestro-addon/pkg/mq"
 )
@@ -19,6 +31,19 @@ type MaestroAddOnManagerOptions struct {
 	messageQueueBrokerType       string
 	messageQueueBrokerConfigPath string
 	maestroServiceAddress        string
+
+	// the interval to describe the consumer groups of the agents, and the lag threshold to mark the agents as degraded,
+	// the agents are not marked as degraded if the threshold is not positive
+	consumerGroupLagInterval  time.Duration
+	consumerGroupLagThreshold int64
+
+	// the interval to check whether the consumer groups of the agents have active members
+	agentConnectivityInterval time.Duration
+
+	// the address to serve the /readyz and /livez health probes, and the timeout after which the worker queue of the
+	// ManagedClusterController is reported as stuck
+	healthProbeBindAddress string
+	workerStuckTimeout     time.Duration
 }
 
 func NewMaestroAddOnManagerOptions() *MaestroAddOnManagerOptions {
@@ -26,6 +51,10 @@ func NewMaestroAddOnManagerOptions() *MaestroAddOnManagerOptions {
 		maestroServiceAddress:        defaultMaestroServiceAddress,
 		messageQueueBrokerType:       mq.MessageQueueKafka,
 		messageQueueBrokerConfigPath: "/configs/kafka/config.yaml",
+		consumerGroupLagInterval:     time.Minute,
+		agentConnectivityInterval:    time.Minute,
+		healthProbeBindAddress:       ":8081",
+		workerStuckTimeout:           5 * time.Minute,
 	}
 }
 
@@ -33,9 +62,57 @@ func (o *MaestroAddOnManagerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.maestroServiceAddress, "maestro-service-address", o.maestroServiceAddress,
 		"Address of the Maestro API service")
 	fs.StringVar(&o.messageQueueBrokerType, "message-queue-broker-type", o.messageQueueBrokerType,
-		"Type of message queue broker")
+		"Type of message queue broker, it can be kafka, strimzi, mqtt or none")
 	fs.StringVar(&o.messageQueueBrokerConfigPath, "message-queue-broker-config", o.messageQueueBrokerConfigPath,
-		"Path to the message queue broker configuration file")
+		"Path to the message queue broker configuration file, or secret://<namespace>/<name> to read the "+
+			"configuration from a Secret")
+	fs.DurationVar(&o.consumerGroupLagInterval, "consumer-group-lag-interval", o.consumerGroupLagInterval,
+		"Interval to describe the Kafka consumer groups of the agents and report their lag")
+	fs.Int64Var(&o.consumerGroupLagThreshold, "consumer-group-lag-threshold", o.consumerGroupLagThreshold,
+		"Lag of the Kafka consumer group of an agent that marks its ManagedClusterAddOn as degraded, 0 disables it")
+	fs.DurationVar(&o.agentConnectivityInterval, "agent-connectivity-interval", o.agentConnectivityInterval,
+		"Interval to check whether the Kafka consumer groups of the agents have active members")
+	fs.StringVar(&o.healthProbeBindAddress, "health-probe-bind-address", o.healthProbeBindAddress,
+		"Address to serve the /readyz and /livez health probes, empty disables them")
+	fs.DurationVar(&o.workerStuckTimeout, "worker-stuck-timeout", o.workerStuckTimeout,
+		"Timeout after which the worker queue of the ManagedClusterController is reported as stuck by /livez")
+}
+
+// PrintPrincipal prints the Kafka principal that the ACLs of the agent of the given cluster are bound to, so it can be
+// compared with the principal that the broker maps from the agent certificate. The broker config is loaded in the same
+// way as the manager, the kube config is only used to read the broker config of a secret://<namespace>/<name>.
+func (o *MaestroAddOnManagerOptions) PrintPrincipal(ctx context.Context, kubeConfig *rest.Config,
+	out io.Writer, clusterName string) error {
+	mqConfigPath := o.messageQueueBrokerConfigPath
+	if mq.IsSecretConfig(mqConfigPath) {
+		kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+		if err != nil {
+			return err
+		}
+
+		dir, err := os.MkdirTemp("", "maestro-addon-broker-config")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		// the secret is only read once, the informer is stopped after the config is written
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		mqConfigPath, err = mq.WatchSecretConfig(watchCtx, kubeClient, o.messageQueueBrokerConfigPath, dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	principal, err := mq.ToAgentPrincipal(o.messageQueueBrokerType, mqConfigPath, clusterName)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, principal)
+	return err
 }
 
 func (o *MaestroAddOnManagerOptions) RunHubManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
@@ -44,24 +121,113 @@ func (o *MaestroAddOnManagerOptions) RunHubManager(ctx context.Context, controll
 		return err
 	}
 
+	kubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
+	addOnClient, err := addonclientset.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
 	clusterInformers := clusterinformers.NewSharedInformerFactory(clusterClient, 30*time.Minute)
+	addOnInformers := addoninformers.NewSharedInformerFactory(addOnClient, 30*time.Minute)
+
+	// the broker config of a secret is written to the files in a temporary directory and rewritten when the secret
+	// is updated, then the creators reload it from the files
+	mqConfigPath := o.messageQueueBrokerConfigPath
+	if mq.IsSecretConfig(mqConfigPath) {
+		mqConfigPath, err = mq.WatchSecretConfig(ctx, kubeClient, o.messageQueueBrokerConfigPath,
+			filepath.Join(os.TempDir(), "maestro-addon-broker-config"))
+		if err != nil {
+			return err
+		}
+	}
 
-	mqAuthzCreator, err := mq.NewMessageQueueAuthzCreator(o.messageQueueBrokerType, o.messageQueueBrokerConfigPath)
+	mqAuthzCreator, err := mq.NewMessageQueueAuthzCreator(o.messageQueueBrokerType, mqConfigPath,
+		addOnClient.AddonV1alpha1(), dynamicClient)
 	if err != nil {
 		return err
 	}
+	// the creators that hold the connections to the message queue broker are closed when the manager shuts down
+	if closer, ok := mqAuthzCreator.(io.Closer); ok {
+		defer closer.Close()
+	}
+	// the creators reload the broker config when the config files are changed, e.g. the client certificate is rotated
+	if watcher, ok := mqAuthzCreator.(mq.ConfigWatcher); ok {
+		go watcher.WatchConfig(ctx)
+	}
+	// the message queue broker is bootstrapped in the background, the manager does not wait for it, so the maestro
+	// consumers are created when the broker is unreachable
+	if bootstrapper, ok := mqAuthzCreator.(mq.Bootstrapper); ok {
+		go bootstrapper.Bootstrap(ctx)
+	}
 
+	watchdog := controllers.NewSyncWatchdog(o.workerStuckTimeout)
 	managedClusterController := controllers.NewManagedClusterController(
 		o.maestroServiceAddress,
+		clusterClient.ClusterV1().ManagedClusters(),
 		clusterInformers.Cluster().V1().ManagedClusters(),
+		addOnClient.AddonV1alpha1(),
+		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
 		mqAuthzCreator,
+		watchdog,
 		controllerContext.EventRecorder,
 	)
 
+	// the informers that must be synced before the manager is ready
+	syncedInformers := map[string]cache.SharedIndexInformer{
+		"managedclusters":      clusterInformers.Cluster().V1().ManagedClusters().Informer(),
+		"managedclusteraddons": addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Informer(),
+	}
+
 	go clusterInformers.Start(ctx.Done())
+	go addOnInformers.Start(ctx.Done())
 
 	go managedClusterController.Run(ctx, 1)
 
+	// the lag and the connectivity of the agents are reported if the message queue can describe the consumer groups
+	if groupDescriber, ok := mqAuthzCreator.(mq.ConsumerGroupDescriber); ok {
+		consumerGroupLagController := controllers.NewConsumerGroupLagController(
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			addOnClient.AddonV1alpha1(),
+			addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+			groupDescriber,
+			o.consumerGroupLagThreshold,
+			o.consumerGroupLagInterval,
+			controllerContext.EventRecorder,
+		)
+
+		agentConnectivityController := controllers.NewAgentConnectivityController(
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			addOnClient.AddonV1alpha1(),
+			addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+			groupDescriber,
+			o.agentConnectivityInterval,
+			controllerContext.EventRecorder,
+		)
+
+		go consumerGroupLagController.Run(ctx, 1)
+		go agentConnectivityController.Run(ctx, 1)
+	}
+
+	if len(o.healthProbeBindAddress) != 0 {
+		go serveHealthProbes(ctx, o.healthProbeBindAddress,
+			[]healthz.HealthChecker{
+				maestroAPIReadyzCheck(helpers.NewMaestroAPIClient(o.maestroServiceAddress)),
+				messageQueueReadyzCheck(mqAuthzCreator),
+				informerSyncReadyzCheck(syncedInformers),
+			},
+			[]healthz.HealthChecker{workerQueueLivezCheck(watchdog)},
+		)
+	}
+
 	<-ctx.Done()
 	return nil
 }
@@ -4,39 +4,173 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
 
 	"github.com/stolostron/maestro-addon/pkg/helpers"
 )
 
 const MessageQueueKafka = "kafka"
 
-const sourceID = "maestro"
+// MessageQueueNone disables the message queue authorizations, the authorizations of the agents are managed
+// out of the maestro-addon.
+const MessageQueueNone = "none"
+
+// MessageQueueGRPC is the Maestro gRPC broker, it is rejected because the broker does not authorize the agents, so the
+// authorizations cannot be created for the agents.
+const MessageQueueGRPC = "grpc"
+
+// defaultSourceID is the default source ID of the Maestro server.
+const defaultSourceID = "maestro"
+
+// kafkaNameRegex matches the characters that are legal in the Kafka topic and consumer group names.
+var kafkaNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]*$`)
 
 type MessageQueueAuthzCreator interface {
 	CreateAuthorizations(ctx context.Context, clusterName string) error
 	DeleteAuthorizations(ctx context.Context, clusterName string) error
 }
 
-func NewMessageQueueAuthzCreator(mqType, mqConfigPath string) (MessageQueueAuthzCreator, error) {
+// BulkMessageQueueAuthzCreator is implemented by the creators that can create the authorizations of many clusters in
+// one pass, e.g. the missing Kafka ACLs of all clusters are created with one CreateACLs call.
+type BulkMessageQueueAuthzCreator interface {
+	CreateBulkAuthorizations(ctx context.Context, clusterNames []string) error
+}
+
+// QuotaMessageQueueAuthzCreator is implemented by the creators that can limit the agents with the Kafka client quotas,
+// the given overrides take precedence over the default quotas of the creator.
+type QuotaMessageQueueAuthzCreator interface {
+	CreateAuthorizationsWithQuotas(ctx context.Context, clusterName string, overrides helpers.KafkaClientQuotas) error
+}
+
+// ConsumerGroupDescriber is implemented by the creators that can describe the consumer groups of the agents, e.g. the
+// lag of the consumer group of an agent on the source events topic.
+type ConsumerGroupDescriber interface {
+	// DescribeAgentConsumerGroups describes the states and the active members of the consumer groups of the agents of
+	// the given clusters in bulk, the lag is not computed.
+	DescribeAgentConsumerGroups(ctx context.Context,
+		clusterNames []string) (map[string]helpers.KafkaConsumerGroupStatus, error)
+	// DescribeAgentConsumerGroupsLag describes the consumer groups of the agents of the given clusters in bulk, the
+	// clusters whose consumer groups cannot be described are not in the result.
+	DescribeAgentConsumerGroupsLag(ctx context.Context,
+		clusterNames []string) (map[string]helpers.KafkaConsumerGroupStatus, error)
+}
+
+// ConnectivityChecker is implemented by the creators that hold a connection to the message queue broker, it returns
+// an error if the broker cannot be reached with the connection.
+type ConnectivityChecker interface {
+	CheckConnectivity(ctx context.Context) error
+}
+
+// NewMessageQueueAuthzCreator returns a creator for the given message queue type, the addOnClient is used to deliver
+// the agent credentials with the addon configs, it is only required by the scram agent authentication. The
+// dynamicClient is used to manage the Strimzi KafkaUser resources, it is only required by the strimzi message queue.
+// A nil creator is returned for the none message queue type.
+func NewMessageQueueAuthzCreator(mqType, mqConfigPath string,
+	addOnClient addonv1alpha1client.AddonV1alpha1Interface, dynamicClient dynamic.Interface) (MessageQueueAuthzCreator, error) {
 	switch mqType {
 	case MessageQueueKafka:
-		config, err := ToKafkaConfigMap(mqConfigPath)
+		config, err := LoadKafkaConfig(mqConfigPath)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := helpers.CreteKafkaTopics(context.Background(), config, sourceID); err != nil {
+		configMap, err := toKafkaConfigMap(config)
+		if err != nil {
 			return nil, err
 		}
 
-		return &KafkaAuthzCreator{config: config}, nil
-	default:
-		klog.Warningf("unsupported message queue driver: %s, will not create message queue authorizations", mqType)
+		topicSpecs := toKafkaTopicSpecs(config.Topics)
+
+		// the admin client is shared by the reconciles, it is closed when the manager shuts down. The admin client does
+		// not connect to the broker until it is used, the shared topics and the source ACLs are created by the Bootstrap
+		// in the background, so the manager can start when the broker is unreachable.
+		adminClient := helpers.NewSharedKafkaAdminClient(configMap)
+
+		var sourceACLOptions *helpers.KafkaSourceACLOptions
+		if config.SourcePrincipal != "" {
+			sourceACLOptions = &helpers.KafkaSourceACLOptions{
+				Principal:       config.SourcePrincipal,
+				GroupID:         config.SourceGroupID,
+				RemoveStaleACLs: config.RemoveStaleACLs,
+			}
+		}
+
+		agentAuthentication := helpers.KafkaAgentAuthentication(config.AgentAuthentication)
+		if agentAuthentication == helpers.KafkaAgentAuthenticationScram && addOnClient == nil {
+			adminClient.Close()
+			return nil, fmt.Errorf("the scram agent authentication requires an addon client")
+		}
+
+		// the mechanism is validated when the config is loaded
+		scramMechanism, _ := kafka.ScramMechanismFromString(config.AgentScramMechanism)
+
+		aclOptions, err := toKafkaACLOptions(config)
+		if err != nil {
+			adminClient.Close()
+			return nil, err
+		}
+
+		// the checksum of the config files is used to detect the changes of the files
+		configChecksum, err := checksumFiles(toKafkaConfigFiles(mqConfigPath, config))
+		if err != nil {
+			adminClient.Close()
+			return nil, err
+		}
+
+		return &KafkaAuthzCreator{
+			adminClient:      adminClient,
+			addOnClient:      addOnClient,
+			topicSpecs:       topicSpecs,
+			scramMechanism:   scramMechanism,
+			aclOptions:       aclOptions,
+			sourceACLOptions: sourceACLOptions,
+			clientQuotas:     config.ClientQuotas,
+			configPath:       mqConfigPath,
+			config:           config,
+			configChecksum:   configChecksum,
+		}, nil
+	case MessageQueueMQTT:
+		config, err := LoadMQTTConfig(mqConfigPath)
+		if err != nil {
+			return nil, err
+		}
+
+		options, err := toMQTTDialOptions(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return &MQTTAuthzCreator{client: helpers.NewMosquittoDynSecClient(options), sourceID: config.SourceID}, nil
+	case MessageQueueGRPC:
+		return nil, fmt.Errorf("the grpc message queue is not supported, the Maestro gRPC broker does not authorize "+
+			"the agents, use the %s message queue type for the gRPC broker", MessageQueueNone)
+	case MessageQueueStrimzi:
+		if dynamicClient == nil {
+			return nil, fmt.Errorf("the strimzi message queue requires a dynamic client")
+		}
+
+		config, err := LoadStrimziConfig(mqConfigPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return newStrimziAuthzCreator(dynamicClient, config)
+	case MessageQueueNone:
+		klog.Warningf("the message queue type is %s, will not create message queue authorizations", mqType)
 		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported message queue type %q, it must be %s, %s, %s or %s",
+			mqType, MessageQueueKafka, MessageQueueStrimzi, MessageQueueMQTT, MessageQueueNone)
 	}
 }
 
@@ -50,9 +184,150 @@ type KafkaConfig struct {
 	ClientCertFile string `json:"clientCertFile,omitempty" yaml:"clientCertFile,omitempty"`
 	// ClientKeyFile is the file path to a client key file for TLS.
 	ClientKeyFile string `json:"clientKeyFile,omitempty" yaml:"clientKeyFile,omitempty"`
+
+	// SASL is the SASL authentication config, it can not be set together with the client cert and key.
+	SASL *KafkaSASLConfig `json:"sasl,omitempty" yaml:"sasl,omitempty"`
+
+	// ACLProfile is the profile of the ACLs that are granted to the agents, it can be restricted or permissive,
+	// defaults to restricted. The permissive profile grants all operations on the event topics and all consumer
+	// groups, it only is kept for migrating existing clusters.
+	ACLProfile string `json:"aclProfile,omitempty" yaml:"aclProfile,omitempty"`
+
+	// RemoveStaleACLs removes the ACLs of the agents that are not granted by the ACL profile on each reconcile.
+	RemoveStaleACLs bool `json:"removeStaleACLs,omitempty" yaml:"removeStaleACLs,omitempty"`
+
+	// TopicLayout is the layout of the event topics, only the shared layout is supported, all clusters share the
+	// sourceevents and agentevents topics. The perCluster layout is rejected until the Maestro server and the agents
+	// support the per-cluster topics.
+	TopicLayout string `json:"topicLayout,omitempty" yaml:"topicLayout,omitempty"`
+
+	// AgentAuthentication is how the agents authenticate to the Kafka broker, it can be certificate or scram,
+	// defaults to certificate. The scram creates a SCRAM user for each cluster and stores its credential in the
+	// maestro-addon-kafka-scram AddOnDeploymentConfig in the cluster namespace, the ACLs of the agent are bound to the
+	// SCRAM user.
+	AgentAuthentication string `json:"agentAuthentication,omitempty" yaml:"agentAuthentication,omitempty"`
+
+	// AgentScramMechanism is the SCRAM mechanism of the agent SCRAM users, it can be SCRAM-SHA-256 or SCRAM-SHA-512,
+	// defaults to SCRAM-SHA-512.
+	AgentScramMechanism string `json:"agentScramMechanism,omitempty" yaml:"agentScramMechanism,omitempty"`
+
+	// Topics are the specifications of the event topics.
+	Topics KafkaTopicsConfig `json:"topics,omitempty" yaml:"topics,omitempty"`
+
+	// SourcePrincipal is the principal of the Maestro server, e.g. User:CN=maestro. If it is set, the least privilege
+	// ACLs are created for the Maestro server, so it does not need to be a super user: write on the source events
+	// topic, read on the agent events topic and read with its own consumer group.
+	SourcePrincipal string `json:"sourcePrincipal,omitempty" yaml:"sourcePrincipal,omitempty"`
+
+	// SourceGroupID is the consumer group of the Maestro server, defaults to the SourceID.
+	SourceGroupID string `json:"sourceGroupID,omitempty" yaml:"sourceGroupID,omitempty"`
+
+	// SourceID is the source ID of the Maestro server, it must be same with the --source-id of the Maestro server,
+	// defaults to maestro.
+	SourceID string `json:"sourceID,omitempty" yaml:"sourceID,omitempty"`
+
+	// TopicPrefix is reserved for the Maestro server and agents that support the prefixed topics, a non-empty prefix
+	// is rejected for now.
+	TopicPrefix string `json:"topicPrefix,omitempty" yaml:"topicPrefix,omitempty"`
+
+	// PrincipalPreset is the predefined template of the agent certificate principal, it can be dn or cn, defaults
+	// to dn. The principal must match the principal that the broker maps with its ssl.principal.mapping.rules.
+	PrincipalPreset string `json:"principalPreset,omitempty" yaml:"principalPreset,omitempty"`
+
+	// PrincipalTemplate is a Go template of the agent certificate principal, it takes precedence over the
+	// PrincipalPreset. The .ClusterName, .AddOnName, .CommonName and .Groups can be used in the template.
+	PrincipalTemplate string `json:"principalTemplate,omitempty" yaml:"principalTemplate,omitempty"`
+
+	// ClientQuotas are the default client quotas of the agents, they can be overridden for a cluster with the
+	// maestro-addon.open-cluster-management.io/kafka-client-quotas annotation of the ManagedCluster.
+	ClientQuotas helpers.KafkaClientQuotas `json:"clientQuotas,omitempty" yaml:"clientQuotas,omitempty"`
+}
+
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismScramSHA256 = "SCRAM-SHA-256"
+	SASLMechanismScramSHA512 = "SCRAM-SHA-512"
+	SASLMechanismOAuthBearer = "OAUTHBEARER"
+)
+
+type KafkaSASLConfig struct {
+	// Mechanism is the SASL mechanism, it can be PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER.
+	Mechanism string `json:"mechanism" yaml:"mechanism"`
+
+	// UsernameFile is the file path to the username for the PLAIN and SCRAM mechanisms.
+	UsernameFile string `json:"usernameFile,omitempty" yaml:"usernameFile,omitempty"`
+	// PasswordFile is the file path to the password for the PLAIN and SCRAM mechanisms.
+	PasswordFile string `json:"passwordFile,omitempty" yaml:"passwordFile,omitempty"`
+
+	// TokenEndpointURL is the OAuth/OIDC token endpoint URL for the OAUTHBEARER mechanism.
+	TokenEndpointURL string `json:"tokenEndpointURL,omitempty" yaml:"tokenEndpointURL,omitempty"`
+	// ClientID is the OAuth client ID for the OAUTHBEARER mechanism.
+	ClientID string `json:"clientID,omitempty" yaml:"clientID,omitempty"`
+	// ClientSecretFile is the file path to the OAuth client secret for the OAUTHBEARER mechanism.
+	ClientSecretFile string `json:"clientSecretFile,omitempty" yaml:"clientSecretFile,omitempty"`
+	// Scope is the OAuth scope of the access token for the OAUTHBEARER mechanism, it is optional.
+	Scope string `json:"scope,omitempty" yaml:"scope,omitempty"`
+}
+
+type KafkaTopicsConfig struct {
+	// SourceEvents is the specification of the topic that the sources publish their events to.
+	SourceEvents KafkaTopicConfig `json:"sourceEvents,omitempty" yaml:"sourceEvents,omitempty"`
+	// AgentEvents is the specification of the topic that the agents publish their events to.
+	AgentEvents KafkaTopicConfig `json:"agentEvents,omitempty" yaml:"agentEvents,omitempty"`
+}
+
+type KafkaTopicConfig struct {
+	// Partitions is the number of partitions of the topic, defaults to 50. The partitions of an existing topic are
+	// expanded if they are less.
+	Partitions int `json:"partitions,omitempty" yaml:"partitions,omitempty"`
+	// ReplicationFactor is the replication factor of the topic, defaults to 1.
+	ReplicationFactor int `json:"replicationFactor,omitempty" yaml:"replicationFactor,omitempty"`
+	// Configs are the configs of the topic, e.g. retention.ms, min.insync.replicas and cleanup.policy, they
+	// are applied when the topic is created.
+	Configs map[string]string `json:"configs,omitempty" yaml:"configs,omitempty"`
 }
 
 func ToKafkaConfigMap(configPath string) (*kafka.ConfigMap, error) {
+	config, err := LoadKafkaConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return toKafkaConfigMap(config)
+}
+
+// ToKafkaACLOptions loads the Kafka config from the given file and returns the options that the ACLs of the agents are
+// created with.
+func ToKafkaACLOptions(configPath string) (helpers.KafkaACLOptions, error) {
+	config, err := LoadKafkaConfig(configPath)
+	if err != nil {
+		return helpers.KafkaACLOptions{}, err
+	}
+
+	return toKafkaACLOptions(config)
+}
+
+// kafkaTopicLayoutShared is the only supported topic layout, all clusters share the sourceevents and agentevents
+// topics.
+const kafkaTopicLayoutShared = "shared"
+
+// validateKafkaTopicLayout rejects the perCluster topic layout, the Kafka clients of the Maestro server and the agents
+// in this release only publish and subscribe the shared sourceevents and agentevents topics, so the per-cluster topics
+// would break the onboarding of the clusters.
+func validateKafkaTopicLayout(topicLayout string) error {
+	switch topicLayout {
+	case "", kafkaTopicLayoutShared:
+		return nil
+	case "perCluster":
+		return fmt.Errorf("the topicLayout %q is not supported, the Maestro server and the agents use the shared "+
+			"sourceevents and agentevents topics", topicLayout)
+	default:
+		return fmt.Errorf("unsupported topicLayout %q, it must be %s", topicLayout, kafkaTopicLayoutShared)
+	}
+}
+
+// LoadKafkaConfig loads the Kafka config from the given file and validates it.
+func LoadKafkaConfig(configPath string) (*KafkaConfig, error) {
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, err
@@ -63,6 +338,17 @@ func ToKafkaConfigMap(configPath string) (*kafka.ConfigMap, error) {
 		return nil, err
 	}
 
+	// the relative file paths are relative to the directory of the config file
+	configDir := filepath.Dir(configPath)
+	config.CAFile = toAbsolutePath(configDir, config.CAFile)
+	config.ClientCertFile = toAbsolutePath(configDir, config.ClientCertFile)
+	config.ClientKeyFile = toAbsolutePath(configDir, config.ClientKeyFile)
+	if config.SASL != nil {
+		config.SASL.UsernameFile = toAbsolutePath(configDir, config.SASL.UsernameFile)
+		config.SASL.PasswordFile = toAbsolutePath(configDir, config.SASL.PasswordFile)
+		config.SASL.ClientSecretFile = toAbsolutePath(configDir, config.SASL.ClientSecretFile)
+	}
+
 	if config.BootstrapServer == "" {
 		return nil, fmt.Errorf("bootstrapServer is required")
 	}
@@ -75,6 +361,205 @@ func ToKafkaConfigMap(configPath string) (*kafka.ConfigMap, error) {
 		return nil, fmt.Errorf("setting clientCertFile and clientKeyFile requires caFile")
 	}
 
+	if config.SASL != nil {
+		if config.ClientCertFile != "" {
+			return nil, fmt.Errorf("sasl can not be set together with clientCertFile and clientKeyFile")
+		}
+
+		if err := validateKafkaSASLConfig(config.SASL); err != nil {
+			return nil, err
+		}
+	}
+
+	switch helpers.KafkaACLProfile(config.ACLProfile) {
+	case "":
+		config.ACLProfile = string(helpers.KafkaACLProfileRestricted)
+	case helpers.KafkaACLProfileRestricted, helpers.KafkaACLProfilePermissive:
+	default:
+		return nil, fmt.Errorf("unsupported aclProfile %q, it must be %s or %s", config.ACLProfile,
+			helpers.KafkaACLProfileRestricted, helpers.KafkaACLProfilePermissive)
+	}
+
+	if err := validateKafkaTopicLayout(config.TopicLayout); err != nil {
+		return nil, err
+	}
+	config.TopicLayout = kafkaTopicLayoutShared
+
+	switch helpers.KafkaAgentAuthentication(config.AgentAuthentication) {
+	case "":
+		config.AgentAuthentication = string(helpers.KafkaAgentAuthenticationCertificate)
+	case helpers.KafkaAgentAuthenticationCertificate, helpers.KafkaAgentAuthenticationScram:
+	default:
+		return nil, fmt.Errorf("unsupported agentAuthentication %q, it must be %s or %s", config.AgentAuthentication,
+			helpers.KafkaAgentAuthenticationCertificate, helpers.KafkaAgentAuthenticationScram)
+	}
+
+	switch config.AgentScramMechanism {
+	case "":
+		config.AgentScramMechanism = SASLMechanismScramSHA512
+	case SASLMechanismScramSHA256, SASLMechanismScramSHA512:
+	default:
+		return nil, fmt.Errorf("unsupported agentScramMechanism %q, it must be %s or %s", config.AgentScramMechanism,
+			SASLMechanismScramSHA256, SASLMechanismScramSHA512)
+	}
+
+	if config.SourcePrincipal != "" && !strings.HasPrefix(config.SourcePrincipal, "User:") {
+		return nil, fmt.Errorf("the sourcePrincipal %q must be in the format User:<name>", config.SourcePrincipal)
+	}
+	if config.SourceID == "" {
+		config.SourceID = defaultSourceID
+	}
+	if !kafkaNameRegex.MatchString(config.SourceID) {
+		return nil, fmt.Errorf("the sourceID %q must only contain the characters a-z, A-Z, 0-9, '.', '_' and '-'",
+			config.SourceID)
+	}
+	if config.SourceGroupID == "" {
+		config.SourceGroupID = config.SourceID
+	}
+
+	// the Maestro server and the agents publish and subscribe the fixed sourceevents and agentevents topics, so the
+	// prefixed topics would break the onboarding of the clusters
+	if config.TopicPrefix != "" {
+		return nil, fmt.Errorf("the topicPrefix %q is not supported, the Maestro server and the agents use the "+
+			"unprefixed sourceevents and agentevents topics", config.TopicPrefix)
+	}
+
+	if _, err := helpers.NewKafkaPrincipalTemplate(
+		helpers.KafkaPrincipalPreset(config.PrincipalPreset), config.PrincipalTemplate); err != nil {
+		return nil, err
+	}
+
+	if err := validateKafkaTopicConfig("sourceEvents", config.Topics.SourceEvents); err != nil {
+		return nil, err
+	}
+	if err := validateKafkaTopicConfig("agentEvents", config.Topics.AgentEvents); err != nil {
+		return nil, err
+	}
+
+	if err := config.ClientQuotas.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid clientQuotas: %v", err)
+	}
+
+	return config, nil
+}
+
+// ToAgentPrincipal loads the broker config of the given message queue type from the given file and returns the
+// principal that the ACLs of the agent of the given cluster are bound to. For the strimzi message queue, it is the
+// principal that Strimzi binds the ACLs of the KafkaUser of the agent to.
+func ToAgentPrincipal(mqType, configPath, clusterName string) (string, error) {
+	switch mqType {
+	case MessageQueueKafka:
+		config, err := LoadKafkaConfig(configPath)
+		if err != nil {
+			return "", err
+		}
+
+		aclOptions, err := toKafkaACLOptions(config)
+		if err != nil {
+			return "", err
+		}
+
+		return helpers.ToAgentPrincipal(aclOptions, clusterName)
+	case MessageQueueStrimzi:
+		config, err := LoadStrimziConfig(configPath)
+		if err != nil {
+			return "", err
+		}
+
+		principalTemplate, err := helpers.NewKafkaPrincipalTemplate("", config.PrincipalTemplate)
+		if err != nil {
+			return "", err
+		}
+
+		name, err := toKafkaUserName(principalTemplate, clusterName)
+		if err != nil {
+			return "", err
+		}
+
+		return strimziPrincipalPrefix + name, nil
+	default:
+		return "", fmt.Errorf("the principal only can be printed for the %s and %s message queues",
+			MessageQueueKafka, MessageQueueStrimzi)
+	}
+}
+
+func toKafkaACLOptions(config *KafkaConfig) (helpers.KafkaACLOptions, error) {
+	principalTemplate, err := helpers.NewKafkaPrincipalTemplate(
+		helpers.KafkaPrincipalPreset(config.PrincipalPreset), config.PrincipalTemplate)
+	if err != nil {
+		return helpers.KafkaACLOptions{}, err
+	}
+
+	return helpers.KafkaACLOptions{
+		Profile:             helpers.KafkaACLProfile(config.ACLProfile),
+		AgentAuthentication: helpers.KafkaAgentAuthentication(config.AgentAuthentication),
+		RemoveStaleACLs:     config.RemoveStaleACLs,
+		PrincipalTemplate:   principalTemplate,
+	}, nil
+}
+
+func validateKafkaSASLConfig(config *KafkaSASLConfig) error {
+	switch config.Mechanism {
+	case SASLMechanismPlain, SASLMechanismScramSHA256, SASLMechanismScramSHA512:
+		if config.UsernameFile == "" || config.PasswordFile == "" {
+			return fmt.Errorf("sasl mechanism %s requires usernameFile and passwordFile", config.Mechanism)
+		}
+	case SASLMechanismOAuthBearer:
+		if config.TokenEndpointURL == "" {
+			return fmt.Errorf("sasl mechanism %s requires tokenEndpointURL", config.Mechanism)
+		}
+		if config.ClientID == "" || config.ClientSecretFile == "" {
+			return fmt.Errorf("sasl mechanism %s requires clientID and clientSecretFile", config.Mechanism)
+		}
+	default:
+		return fmt.Errorf("unsupported sasl mechanism %q, it must be %s, %s, %s or %s", config.Mechanism,
+			SASLMechanismPlain, SASLMechanismScramSHA256, SASLMechanismScramSHA512, SASLMechanismOAuthBearer)
+	}
+
+	return nil
+}
+
+func validateKafkaTopicConfig(name string, config KafkaTopicConfig) error {
+	if config.Partitions < 0 {
+		return fmt.Errorf("the partitions of topic %s must not be negative", name)
+	}
+	if config.ReplicationFactor < 0 {
+		return fmt.Errorf("the replicationFactor of topic %s must not be negative", name)
+	}
+
+	replicationFactor := config.ReplicationFactor
+	if replicationFactor == 0 {
+		replicationFactor = 1
+	}
+
+	for key, value := range config.Configs {
+		switch key {
+		case "":
+			return fmt.Errorf("the config key of topic %s must not be empty", name)
+		case "retention.ms":
+			retention, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || retention < -1 {
+				return fmt.Errorf("the retention.ms of topic %s must be an integer that is not less than -1", name)
+			}
+		case "min.insync.replicas":
+			minInSyncReplicas, err := strconv.Atoi(value)
+			if err != nil || minInSyncReplicas < 1 || minInSyncReplicas > replicationFactor {
+				return fmt.Errorf("the min.insync.replicas of topic %s must be an integer between 1 and %d",
+					name, replicationFactor)
+			}
+		case "cleanup.policy":
+			for _, policy := range strings.Split(value, ",") {
+				if policy != "delete" && policy != "compact" {
+					return fmt.Errorf("the cleanup.policy of topic %s must be delete, compact or both", name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func toKafkaConfigMap(config *KafkaConfig) (*kafka.ConfigMap, error) {
 	configMap := &kafka.ConfigMap{
 		"bootstrap.servers": config.BootstrapServer,
 	}
@@ -86,18 +571,198 @@ func ToKafkaConfigMap(configPath string) (*kafka.ConfigMap, error) {
 		_ = configMap.SetKey("ssl.key.location", config.ClientKeyFile)
 	}
 
+	if config.SASL != nil {
+		if err := setKafkaSASLConfig(configMap, config.CAFile, config.SASL); err != nil {
+			return nil, err
+		}
+	}
+
 	return configMap, nil
 }
 
+// setKafkaSASLConfig maps the SASL config to the librdkafka properties, the SASL_SSL is used if the caFile is set,
+// otherwise the SASL_PLAINTEXT is used.
+func setKafkaSASLConfig(configMap *kafka.ConfigMap, caFile string, config *KafkaSASLConfig) error {
+	if caFile != "" {
+		_ = configMap.SetKey("security.protocol", "sasl_ssl")
+		_ = configMap.SetKey("ssl.ca.location", caFile)
+	} else {
+		_ = configMap.SetKey("security.protocol", "sasl_plaintext")
+	}
+
+	_ = configMap.SetKey("sasl.mechanism", config.Mechanism)
+
+	if config.Mechanism == SASLMechanismOAuthBearer {
+		clientSecret, err := readCredentialFile(config.ClientSecretFile)
+		if err != nil {
+			return err
+		}
+
+		_ = configMap.SetKey("sasl.oauthbearer.method", "oidc")
+		_ = configMap.SetKey("sasl.oauthbearer.token.endpoint.url", config.TokenEndpointURL)
+		_ = configMap.SetKey("sasl.oauthbearer.client.id", config.ClientID)
+		_ = configMap.SetKey("sasl.oauthbearer.client.secret", clientSecret)
+		if config.Scope != "" {
+			_ = configMap.SetKey("sasl.oauthbearer.scope", config.Scope)
+		}
+		return nil
+	}
+
+	username, err := readCredentialFile(config.UsernameFile)
+	if err != nil {
+		return err
+	}
+	password, err := readCredentialFile(config.PasswordFile)
+	if err != nil {
+		return err
+	}
+
+	_ = configMap.SetKey("sasl.username", username)
+	_ = configMap.SetKey("sasl.password", password)
+	return nil
+}
+
+func toAbsolutePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func readCredentialFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	credential := strings.TrimSpace(string(data))
+	if credential == "" {
+		return "", fmt.Errorf("the file %s is empty", path)
+	}
+
+	return credential, nil
+}
+
+func toKafkaTopicSpecs(config KafkaTopicsConfig) helpers.KafkaTopicSpecs {
+	return helpers.KafkaTopicSpecs{
+		SourceEvents: kafka.TopicSpecification{
+			NumPartitions:     config.SourceEvents.Partitions,
+			ReplicationFactor: config.SourceEvents.ReplicationFactor,
+			Config:            config.SourceEvents.Configs,
+		},
+		AgentEvents: kafka.TopicSpecification{
+			NumPartitions:     config.AgentEvents.Partitions,
+			ReplicationFactor: config.AgentEvents.ReplicationFactor,
+			Config:            config.AgentEvents.Configs,
+		},
+	}
+}
+
+// KafkaAuthzCreator creates the topics and ACLs of the agents with a long-lived admin client, so the connection to the
+// broker is reused across the reconciles. The admin client is recreated when the config files are changed.
 type KafkaAuthzCreator struct {
-	config *kafka.ConfigMap
+	adminClient    helpers.KafkaAdminClient
+	addOnClient    addonv1alpha1client.AddonV1alpha1Interface
+	topicSpecs     helpers.KafkaTopicSpecs
+	scramMechanism kafka.ScramMechanism
+	aclOptions     helpers.KafkaACLOptions
+	clientQuotas   helpers.KafkaClientQuotas
+
+	// the shared topics and the ACLs of the source are created by the Bootstrap, the authorizations of the clusters are
+	// not created until the bootstrap succeeds
+	sourceACLOptions *helpers.KafkaSourceACLOptions
+	bootstrapLock    sync.RWMutex
+	bootstrapped     bool
+	bootstrapErr     error
+
+	// the config and the checksum of its files are only accessed by the config watcher after the creator is created
+	configPath     string
+	config         *KafkaConfig
+	configChecksum string
 }
 
 func (c *KafkaAuthzCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
-	return helpers.CreateACLs(ctx, c.config, sourceID, clusterName)
+	return c.CreateAuthorizationsWithQuotas(ctx, clusterName, helpers.KafkaClientQuotas{})
+}
+
+// CreateAuthorizationsWithQuotas creates the authorizations of the agent and sets its client quotas with the
+// AlterClientQuotas, the quotas that are not set by the defaults or the overrides are removed.
+func (c *KafkaAuthzCreator) CreateAuthorizationsWithQuotas(ctx context.Context,
+	clusterName string, overrides helpers.KafkaClientQuotas) error {
+	if err := c.Ready(); err != nil {
+		return err
+	}
+
+	if err := c.createAuthorizations(ctx, clusterName); err != nil {
+		return err
+	}
+
+	return helpers.AlterClientQuotas(ctx, c.adminClient, c.aclOptions, clusterName, c.clientQuotas.Merge(overrides))
+}
+
+func (c *KafkaAuthzCreator) createAuthorizations(ctx context.Context, clusterName string) error {
+	if c.aclOptions.AgentAuthentication == helpers.KafkaAgentAuthenticationScram {
+		if err := c.ensureScramUser(ctx, clusterName); err != nil {
+			return err
+		}
+	}
+
+	return helpers.CreateACLs(ctx, c.adminClient, c.aclOptions, clusterName)
+}
+
+func (c *KafkaAuthzCreator) CreateBulkAuthorizations(ctx context.Context, clusterNames []string) error {
+	if err := c.Ready(); err != nil {
+		return err
+	}
+
+	for _, clusterName := range clusterNames {
+		if c.aclOptions.AgentAuthentication == helpers.KafkaAgentAuthenticationScram {
+			if err := c.ensureScramUser(ctx, clusterName); err != nil {
+				return err
+			}
+		}
+	}
+
+	return helpers.CreateBulkACLs(ctx, c.adminClient, c.aclOptions, clusterNames)
 }
 
 func (c *KafkaAuthzCreator) DeleteAuthorizations(ctx context.Context, clusterName string) error {
-	// TODO Delete Kafka ACLs for topics created for the given cluster
+	if err := helpers.DeleteACLs(ctx, c.adminClient, c.aclOptions, clusterName); err != nil {
+		return err
+	}
+
+	if err := helpers.DeleteClientQuotas(ctx, c.adminClient, c.aclOptions, clusterName); err != nil {
+		return err
+	}
+
+	if c.aclOptions.AgentAuthentication == helpers.KafkaAgentAuthenticationScram {
+		return c.deleteScramUser(ctx, clusterName)
+	}
+
+	return nil
+}
+
+// DescribeAgentConsumerGroups describes the consumer groups of the agents of the given clusters without their lag.
+func (c *KafkaAuthzCreator) DescribeAgentConsumerGroups(ctx context.Context,
+	clusterNames []string) (map[string]helpers.KafkaConsumerGroupStatus, error) {
+	return helpers.DescribeAgentConsumerGroups(ctx, c.adminClient, clusterNames)
+}
+
+// DescribeAgentConsumerGroupsLag describes the consumer groups of the agents of the given clusters and their lag.
+func (c *KafkaAuthzCreator) DescribeAgentConsumerGroupsLag(ctx context.Context,
+	clusterNames []string) (map[string]helpers.KafkaConsumerGroupStatus, error) {
+	return helpers.DescribeAgentConsumerGroupsLag(ctx, c.adminClient, clusterNames)
+}
+
+// CheckConnectivity describes the Kafka cluster with the admin client, so the bootstrap server, TLS and SASL settings
+// are verified with the broker.
+func (c *KafkaAuthzCreator) CheckConnectivity(ctx context.Context) error {
+	_, err := c.adminClient.DescribeCluster(ctx)
+	return err
+}
+
+// Close closes the admin client of the creator.
+func (c *KafkaAuthzCreator) Close() error {
+	c.adminClient.Close()
 	return nil
 }
@@ -0,0 +1,498 @@
+package mq
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fakeaddon "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+)
+
+func TestLoadKafkaConfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		config        string
+		expectedError bool
+		validate      func(t *testing.T, config *KafkaConfig)
+	}{
+		{
+			name:          "bootstrap server is required",
+			config:        "caFile: /ca.crt",
+			expectedError: true,
+		},
+		{
+			name:   "default config",
+			config: "bootstrapServer: kafka:9092",
+			validate: func(t *testing.T, config *KafkaConfig) {
+				if config.ACLProfile != "restricted" {
+					t.Errorf("unexpected acl profile %s", config.ACLProfile)
+				}
+				if config.TopicLayout != "shared" {
+					t.Errorf("unexpected topic layout %s", config.TopicLayout)
+				}
+				if config.AgentAuthentication != "certificate" {
+					t.Errorf("unexpected agent authentication %s", config.AgentAuthentication)
+				}
+			},
+		},
+		{
+			name: "topic specifications",
+			config: `bootstrapServer: kafka:9092
+topics:
+  sourceEvents:
+    partitions: 10
+    replicationFactor: 3
+    configs:
+      retention.ms: 86400000
+      min.insync.replicas: 2
+      cleanup.policy: delete
+  agentEvents:
+    partitions: 20
+`,
+			validate: func(t *testing.T, config *KafkaConfig) {
+				sourceEvents := config.Topics.SourceEvents
+				if sourceEvents.Partitions != 10 || sourceEvents.ReplicationFactor != 3 {
+					t.Errorf("unexpected source events topic %v", sourceEvents)
+				}
+				if sourceEvents.Configs["retention.ms"] != "86400000" || sourceEvents.Configs["min.insync.replicas"] != "2" {
+					t.Errorf("unexpected source events topic configs %v", sourceEvents.Configs)
+				}
+				if config.Topics.AgentEvents.Partitions != 20 {
+					t.Errorf("unexpected agent events topic %v", config.Topics.AgentEvents)
+				}
+			},
+		},
+		{
+			name: "negative partitions",
+			config: `bootstrapServer: kafka:9092
+topics:
+  sourceEvents:
+    partitions: -1
+`,
+			expectedError: true,
+		},
+		{
+			name: "min.insync.replicas is greater than replication factor",
+			config: `bootstrapServer: kafka:9092
+topics:
+  agentEvents:
+    replicationFactor: 1
+    configs:
+      min.insync.replicas: 2
+`,
+			expectedError: true,
+		},
+		{
+			name: "invalid retention.ms",
+			config: `bootstrapServer: kafka:9092
+topics:
+  agentEvents:
+    configs:
+      retention.ms: 1d
+`,
+			expectedError: true,
+		},
+		{
+			name: "invalid cleanup.policy",
+			config: `bootstrapServer: kafka:9092
+topics:
+  agentEvents:
+    configs:
+      cleanup.policy: remove
+`,
+			expectedError: true,
+		},
+		{
+			name: "sasl with client cert",
+			config: `bootstrapServer: kafka:9092
+caFile: /ca.crt
+clientCertFile: /client.crt
+clientKeyFile: /client.key
+sasl:
+  mechanism: SCRAM-SHA-512
+  usernameFile: /username
+  passwordFile: /password
+`,
+			expectedError: true,
+		},
+		{
+			name: "scram without password",
+			config: `bootstrapServer: kafka:9092
+sasl:
+  mechanism: SCRAM-SHA-512
+  usernameFile: /username
+`,
+			expectedError: true,
+		},
+		{
+			name: "oauthbearer without token endpoint",
+			config: `bootstrapServer: kafka:9092
+sasl:
+  mechanism: OAUTHBEARER
+  clientID: maestro-addon
+  clientSecretFile: /secret
+`,
+			expectedError: true,
+		},
+		{
+			name: "unsupported sasl mechanism",
+			config: `bootstrapServer: kafka:9092
+sasl:
+  mechanism: GSSAPI
+`,
+			expectedError: true,
+		},
+		{
+			name: "unsupported agent authentication",
+			config: `bootstrapServer: kafka:9092
+agentAuthentication: token
+`,
+			expectedError: true,
+		},
+		{
+			name: "unsupported agent scram mechanism",
+			config: `bootstrapServer: kafka:9092
+agentAuthentication: scram
+agentScramMechanism: SCRAM-SHA-1
+`,
+			expectedError: true,
+		},
+		{
+			name: "unsupported acl profile",
+			config: `bootstrapServer: kafka:9092
+aclProfile: all
+`,
+			expectedError: true,
+		},
+		{
+			name: "source principal",
+			config: `bootstrapServer: kafka:9092
+sourcePrincipal: User:CN=maestro
+`,
+			validate: func(t *testing.T, config *KafkaConfig) {
+				if config.SourceGroupID != "maestro" {
+					t.Errorf("unexpected source group id %s", config.SourceGroupID)
+				}
+			},
+		},
+		{
+			name: "source id",
+			config: `bootstrapServer: kafka:9092
+sourceID: hub1-maestro
+`,
+			validate: func(t *testing.T, config *KafkaConfig) {
+				if config.SourceID != "hub1-maestro" || config.SourceGroupID != "hub1-maestro" {
+					t.Errorf("unexpected source id %s and group id %s", config.SourceID, config.SourceGroupID)
+				}
+			},
+		},
+		{
+			name: "topic prefix is not supported",
+			config: `bootstrapServer: kafka:9092
+topicPrefix: hub1.
+`,
+			expectedError: true,
+		},
+		{
+			name: "invalid source id",
+			config: `bootstrapServer: kafka:9092
+sourceID: maestro server
+`,
+			expectedError: true,
+		},
+		{
+			name: "invalid source principal",
+			config: `bootstrapServer: kafka:9092
+sourcePrincipal: CN=maestro
+`,
+			expectedError: true,
+		},
+		{
+			name: "principal preset",
+			config: `bootstrapServer: kafka:9092
+principalPreset: cn
+`,
+			validate: func(t *testing.T, config *KafkaConfig) {
+				if config.PrincipalPreset != "cn" {
+					t.Errorf("unexpected principal preset %s", config.PrincipalPreset)
+				}
+			},
+		},
+		{
+			name: "invalid principal template",
+			config: `bootstrapServer: kafka:9092
+principalTemplate: "User:{{ .Cluster }}"
+`,
+			expectedError: true,
+		},
+		{
+			name: "client quotas",
+			config: `bootstrapServer: kafka:9092
+clientQuotas:
+  producerByteRate: 1048576
+  requestPercentage: 50
+`,
+			validate: func(t *testing.T, config *KafkaConfig) {
+				if *config.ClientQuotas.ProducerByteRate != 1048576 || *config.ClientQuotas.RequestPercentage != 50 ||
+					config.ClientQuotas.ConsumerByteRate != nil {
+					t.Errorf("unexpected client quotas %v", config.ClientQuotas)
+				}
+			},
+		},
+		{
+			name: "invalid client quotas",
+			config: `bootstrapServer: kafka:9092
+clientQuotas:
+  requestPercentage: 200
+`,
+			expectedError: true,
+		},
+		{
+			name: "unsupported topic layout",
+			config: `bootstrapServer: kafka:9092
+topicLayout: perTenant
+`,
+			expectedError: true,
+		},
+		{
+			name: "per-cluster topic layout is not supported",
+			config: `bootstrapServer: kafka:9092
+topicLayout: perCluster
+`,
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(c.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadKafkaConfig(configPath)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c.validate(t, config)
+		})
+	}
+}
+
+func TestToKafkaConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	for file, data := range map[string]string{"username": "admin\n", "password": "secret\n", "client-secret": "token"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name           string
+		config         string
+		expectedConfig map[string]string
+	}{
+		{
+			name: "mutual tls",
+			config: `bootstrapServer: kafka:9093
+caFile: /ca.crt
+clientCertFile: /client.crt
+clientKeyFile: /client.key
+`,
+			expectedConfig: map[string]string{
+				"security.protocol":        "ssl",
+				"ssl.ca.location":          "/ca.crt",
+				"ssl.certificate.location": "/client.crt",
+				"ssl.key.location":         "/client.key",
+			},
+		},
+		{
+			name: "scram over tls",
+			config: fmt.Sprintf(`bootstrapServer: kafka:9093
+caFile: /ca.crt
+sasl:
+  mechanism: SCRAM-SHA-512
+  usernameFile: %s
+  passwordFile: %s
+`, filepath.Join(dir, "username"), filepath.Join(dir, "password")),
+			expectedConfig: map[string]string{
+				"security.protocol": "sasl_ssl",
+				"ssl.ca.location":   "/ca.crt",
+				"sasl.mechanism":    "SCRAM-SHA-512",
+				"sasl.username":     "admin",
+				"sasl.password":     "secret",
+			},
+		},
+		{
+			name: "oauthbearer",
+			config: fmt.Sprintf(`bootstrapServer: kafka:9092
+sasl:
+  mechanism: OAUTHBEARER
+  tokenEndpointURL: https://sso/token
+  clientID: maestro-addon
+  clientSecretFile: %s
+  scope: kafka
+`, filepath.Join(dir, "client-secret")),
+			expectedConfig: map[string]string{
+				"security.protocol":                   "sasl_plaintext",
+				"sasl.mechanism":                      "OAUTHBEARER",
+				"sasl.oauthbearer.method":             "oidc",
+				"sasl.oauthbearer.token.endpoint.url": "https://sso/token",
+				"sasl.oauthbearer.client.id":          "maestro-addon",
+				"sasl.oauthbearer.client.secret":      "token",
+				"sasl.oauthbearer.scope":              "kafka",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(c.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			configMap, err := ToKafkaConfigMap(configPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for key, expected := range c.expectedConfig {
+				value, err := configMap.Get(key, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value != expected {
+					t.Errorf("expected %s=%s, but got %v", key, expected, value)
+				}
+			}
+		})
+	}
+}
+
+func TestToKafkaACLOptions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	config := "bootstrapServer: kafka:9092\naclProfile: permissive\nagentAuthentication: scram\nremoveStaleACLs: true"
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	aclOptions, err := ToKafkaACLOptions(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aclOptions.Profile != helpers.KafkaACLProfilePermissive ||
+		aclOptions.AgentAuthentication != helpers.KafkaAgentAuthenticationScram || !aclOptions.RemoveStaleACLs {
+		t.Errorf("unexpected acl options %+v", aclOptions)
+	}
+}
+
+func TestNewMessageQueueAuthzCreator(t *testing.T) {
+	cases := []struct {
+		name          string
+		mqType        string
+		expectedNil   bool
+		expectedError bool
+	}{
+		{
+			name:        "none",
+			mqType:      MessageQueueNone,
+			expectedNil: true,
+		},
+		{
+			name:          "grpc",
+			mqType:        MessageQueueGRPC,
+			expectedNil:   true,
+			expectedError: true,
+		},
+		{
+			name:          "unknown",
+			mqType:        "kakfa",
+			expectedNil:   true,
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			creator, err := NewMessageQueueAuthzCreator(c.mqType, "", fakeaddon.NewSimpleClientset().AddonV1alpha1(), nil)
+			if c.expectedError && err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+			if !c.expectedError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.expectedNil != (creator == nil) {
+				t.Errorf("expected nil creator %v, but got %v", c.expectedNil, creator)
+			}
+		})
+	}
+}
+
+func TestToAgentPrincipal(t *testing.T) {
+	cases := []struct {
+		name              string
+		mqType            string
+		config            string
+		expectedPrincipal string
+		expectedError     bool
+	}{
+		{
+			name:              "kafka scram",
+			mqType:            MessageQueueKafka,
+			config:            "bootstrapServer: kafka:9092\nagentAuthentication: scram",
+			expectedPrincipal: "User:cluster1-maestro-addon-agent",
+		},
+		{
+			name:              "kafka certificate",
+			mqType:            MessageQueueKafka,
+			config:            "bootstrapServer: kafka:9092\nprincipalPreset: cn",
+			expectedPrincipal: "User:system:open-cluster-management:cluster:cluster1:addon:maestro-addon:agent:maestro-addon-agent",
+		},
+		{
+			name:              "strimzi",
+			mqType:            MessageQueueStrimzi,
+			config:            "namespace: amq-streams\nkafkaCluster: kafka",
+			expectedPrincipal: "User:CN=cluster1-maestro-addon-agent",
+		},
+		{
+			name:          "mqtt",
+			mqType:        MessageQueueMQTT,
+			config:        "brokerHost: mqtt:1883",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(c.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			principal, err := ToAgentPrincipal(c.mqType, configPath, "cluster1")
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if principal != c.expectedPrincipal {
+				t.Errorf("expected principal %s, but got %s", c.expectedPrincipal, principal)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+)
+
+// ErrMessageQueueNotReady is returned when the authorizations of a cluster are created before the message queue
+// broker is bootstrapped, the caller should retry later.
+var ErrMessageQueueNotReady = errors.New("the message queue broker is not ready")
+
+// kafkaBootstrapBackoff is the backoff to retry the bootstrap of the Kafka broker, it is capped at 5 minutes.
+var kafkaBootstrapBackoff = wait.Backoff{
+	Duration: 5 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      5 * time.Minute,
+}
+
+// Bootstrapper is implemented by the creators that prepare the message queue broker in the background, e.g. create
+// the shared Kafka topics and the ACLs of the source, so the manager can start when the broker is unreachable.
+type Bootstrapper interface {
+	// Bootstrap prepares the message queue broker, it is retried with backoff until it succeeds or the context is
+	// done.
+	Bootstrap(ctx context.Context)
+	// Ready returns nil if the message queue broker is bootstrapped, otherwise it returns the last bootstrap error.
+	Ready() error
+}
+
+// Bootstrap creates the shared topics and the source ACLs until it succeeds or the context is done.
+func (c *KafkaAuthzCreator) Bootstrap(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+
+	backoff := kafkaBootstrapBackoff
+	for {
+		err := c.bootstrap(ctx)
+		c.setBootstrapError(err)
+		if err == nil {
+			logger.Info("the kafka broker is bootstrapped")
+			return
+		}
+
+		delay := backoff.Step()
+		logger.Error(err, fmt.Sprintf("Failed to bootstrap the kafka broker, retry after %s", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Ready returns nil if the shared topics and the source ACLs are created.
+func (c *KafkaAuthzCreator) Ready() error {
+	c.bootstrapLock.RLock()
+	defer c.bootstrapLock.RUnlock()
+
+	if c.bootstrapped {
+		return nil
+	}
+	if c.bootstrapErr != nil {
+		return fmt.Errorf("%w: %v", ErrMessageQueueNotReady, c.bootstrapErr)
+	}
+	return ErrMessageQueueNotReady
+}
+
+func (c *KafkaAuthzCreator) bootstrap(ctx context.Context) error {
+	if err := helpers.CreteKafkaTopics(ctx, c.adminClient, c.topicSpecs); err != nil {
+		return err
+	}
+
+	if c.sourceACLOptions != nil {
+		if err := helpers.CreateSourceACLs(ctx, c.adminClient, *c.sourceACLOptions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *KafkaAuthzCreator) setBootstrapError(err error) {
+	c.bootstrapLock.Lock()
+	defer c.bootstrapLock.Unlock()
+
+	c.bootstrapped = err == nil
+	c.bootstrapErr = err
+}
@@ -0,0 +1,76 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+// unreachableKafkaAdminClient fails the topic descriptions until the broker is reachable.
+type unreachableKafkaAdminClient struct {
+	*mock.KafkaAdminMockClient
+	failures int
+}
+
+func (c *unreachableKafkaAdminClient) DescribeTopics(ctx context.Context, topics kafka.TopicCollection,
+	options ...kafka.DescribeTopicsAdminOption) (kafka.DescribeTopicsResult, error) {
+	if c.failures > 0 {
+		c.failures--
+		return kafka.DescribeTopicsResult{}, kafka.NewError(kafka.ErrTransport, "broker transport failure", false)
+	}
+	return c.KafkaAdminMockClient.DescribeTopics(ctx, topics, options...)
+}
+
+func TestKafkaBootstrap(t *testing.T) {
+	backoff := kafkaBootstrapBackoff
+	defer func() { kafkaBootstrapBackoff = backoff }()
+	kafkaBootstrapBackoff = wait.Backoff{Duration: 10 * time.Millisecond, Steps: 10}
+
+	adminClient := &unreachableKafkaAdminClient{KafkaAdminMockClient: mock.NewKafkaAdminMockClient(), failures: 2}
+	creator := &KafkaAuthzCreator{
+		adminClient: adminClient,
+		aclOptions: helpers.KafkaACLOptions{
+			Profile:             helpers.KafkaACLProfileRestricted,
+			AgentAuthentication: helpers.KafkaAgentAuthenticationCertificate,
+		},
+		sourceACLOptions: &helpers.KafkaSourceACLOptions{
+			Principal: "User:CN=maestro",
+			GroupID:   "maestro",
+		},
+	}
+
+	if err := creator.CreateAuthorizations(context.Background(), "cluster1"); !errors.Is(err, ErrMessageQueueNotReady) {
+		t.Errorf("expected the message queue is not ready, but got %v", err)
+	}
+	if len(adminClient.ACLs()) != 0 {
+		t.Errorf("expected no acls are created before the bootstrap, but got %v", adminClient.ACLs())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	creator.Bootstrap(ctx)
+
+	if err := creator.Ready(); err != nil {
+		t.Fatalf("expected the message queue is ready, but got %v", err)
+	}
+	if adminClient.failures != 0 {
+		t.Errorf("expected the bootstrap is retried")
+	}
+	if len(adminClient.Topics()) != 2 {
+		t.Errorf("expected the topics are created, but got %v", adminClient.Topics())
+	}
+
+	if err := creator.CreateAuthorizations(context.Background(), "cluster1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(adminClient.ACLs()) == 0 {
+		t.Errorf("expected the acls are created")
+	}
+}
@@ -0,0 +1,133 @@
+package mq
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// kafkaConfigWatchInterval is the interval to check the changes of the config files. The files are polled instead
+// of watched with inotify, because the mounted secrets are updated by replacing the symbolic links.
+const kafkaConfigWatchInterval = 10 * time.Second
+
+// ConfigWatcher is implemented by the creators that reload the message queue broker config when the config file or the
+// files that are referenced by the config are changed.
+type ConfigWatcher interface {
+	WatchConfig(ctx context.Context)
+}
+
+// kafkaConfigSetter is implemented by the admin clients that can be reconnected with a new config.
+type kafkaConfigSetter interface {
+	SetConfig(config *kafka.ConfigMap)
+}
+
+// WatchConfig checks the Kafka config file and the CA, client cert, client key and SASL credential files periodically
+// until the context is done. On change, the config is reloaded and the admin client is recreated with it.
+func (c *KafkaAuthzCreator) WatchConfig(ctx context.Context) {
+	wait.UntilWithContext(ctx, c.reloadConfigIfChanged, kafkaConfigWatchInterval)
+}
+
+func (c *KafkaAuthzCreator) reloadConfigIfChanged(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+
+	checksum, err := checksumFiles(toKafkaConfigFiles(c.configPath, c.config))
+	if err != nil {
+		logger.Error(err, "Failed to read the kafka config files, the current config is kept")
+		return
+	}
+
+	if checksum == c.configChecksum {
+		return
+	}
+
+	if err := c.reloadConfig(ctx); err != nil {
+		logger.Error(err, "Failed to reload the kafka config, the current config is kept")
+	}
+}
+
+// reloadConfig loads and validates the config, the current config is kept if the new config is invalid. The new
+// connection settings (the bootstrap server, TLS and SASL) are applied by recreating the admin client, the changes of
+// the other settings only take effect after the manager is restarted.
+func (c *KafkaAuthzCreator) reloadConfig(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+
+	config, err := LoadKafkaConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	configMap, err := toKafkaConfigMap(config)
+	if err != nil {
+		return err
+	}
+
+	// compute the checksum with the files of the new config, so a change of the file paths is detected
+	checksum, err := checksumFiles(toKafkaConfigFiles(c.configPath, config))
+	if err != nil {
+		return err
+	}
+
+	if setter, ok := c.adminClient.(kafkaConfigSetter); ok {
+		setter.SetConfig(configMap)
+	}
+
+	if !reflect.DeepEqual(withoutKafkaConnection(c.config), withoutKafkaConnection(config)) {
+		logger.Info(fmt.Sprintf("the kafka config %s is changed, the changes other than the connection settings "+
+			"take effect after the manager is restarted", c.configPath))
+	}
+
+	logger.Info(fmt.Sprintf("the kafka config %s is reloaded, the admin client is recreated with the bootstrap server %s",
+		c.configPath, config.BootstrapServer))
+
+	c.config = config
+	c.configChecksum = checksum
+	return nil
+}
+
+// toKafkaConfigFiles returns the config file and the files that are referenced by the config.
+func toKafkaConfigFiles(configPath string, config *KafkaConfig) []string {
+	files := []string{configPath, config.CAFile, config.ClientCertFile, config.ClientKeyFile}
+	if config.SASL != nil {
+		files = append(files, config.SASL.UsernameFile, config.SASL.PasswordFile, config.SASL.ClientSecretFile)
+	}
+	return files
+}
+
+// withoutKafkaConnection returns a copy of the config without the connection settings.
+func withoutKafkaConnection(config *KafkaConfig) KafkaConfig {
+	copied := *config
+	copied.BootstrapServer = ""
+	copied.CAFile = ""
+	copied.ClientCertFile = ""
+	copied.ClientKeyFile = ""
+	copied.SASL = nil
+	return copied
+}
+
+// checksumFiles returns the checksum of the contents of the given files, the empty file paths are skipped.
+func checksumFiles(files []string) (string, error) {
+	hash := sha256.New()
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		// the file path is included, so the same content in a different file is a change
+		hash.Write([]byte(file))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,114 @@
+package mq
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers/mock"
+)
+
+type reconfigurableKafkaAdminClient struct {
+	*mock.KafkaAdminMockClient
+	configs []*kafka.ConfigMap
+}
+
+func (c *reconfigurableKafkaAdminClient) SetConfig(config *kafka.ConfigMap) {
+	c.configs = append(c.configs, config)
+}
+
+func TestReloadKafkaConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(caFile, "ca")
+	writeFile(configPath, "bootstrapServer: kafka:9092\nsasl:\n  mechanism: PLAIN\n  usernameFile: "+
+		filepath.Join(dir, "username")+"\n  passwordFile: "+filepath.Join(dir, "password")+"\ncaFile: "+caFile+"\n")
+	writeFile(filepath.Join(dir, "username"), "admin")
+	writeFile(filepath.Join(dir, "password"), "secret")
+
+	config, err := LoadKafkaConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := checksumFiles(toKafkaConfigFiles(configPath, config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adminClient := &reconfigurableKafkaAdminClient{KafkaAdminMockClient: mock.NewKafkaAdminMockClient()}
+	creator := &KafkaAuthzCreator{
+		adminClient:    adminClient,
+		configPath:     configPath,
+		config:         config,
+		configChecksum: checksum,
+	}
+
+	cases := []struct {
+		name                    string
+		change                  func()
+		expectedBootstrapServer string
+		expectedReloads         int
+	}{
+		{
+			name:                    "no change",
+			change:                  func() {},
+			expectedBootstrapServer: "kafka:9092",
+		},
+		{
+			name:                    "the password is changed",
+			change:                  func() { writeFile(filepath.Join(dir, "password"), "new-secret") },
+			expectedBootstrapServer: "kafka:9092",
+			expectedReloads:         1,
+		},
+		{
+			name:                    "the ca is rotated",
+			change:                  func() { writeFile(caFile, "new-ca") },
+			expectedBootstrapServer: "kafka:9092",
+			expectedReloads:         2,
+		},
+		{
+			name: "the bootstrap server is changed",
+			change: func() {
+				writeFile(configPath, "bootstrapServer: kafka-new:9092\nsasl:\n  mechanism: PLAIN\n  usernameFile: "+
+					filepath.Join(dir, "username")+"\n  passwordFile: "+filepath.Join(dir, "password")+"\ncaFile: "+caFile+"\n")
+			},
+			expectedBootstrapServer: "kafka-new:9092",
+			expectedReloads:         3,
+		},
+		{
+			name:                    "the invalid config is ignored",
+			change:                  func() { writeFile(configPath, "caFile: "+caFile+"\n") },
+			expectedBootstrapServer: "kafka-new:9092",
+			expectedReloads:         3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.change()
+			creator.reloadConfigIfChanged(context.Background())
+
+			if creator.config.BootstrapServer != c.expectedBootstrapServer {
+				t.Errorf("expected bootstrap server %s, but got %s", c.expectedBootstrapServer, creator.config.BootstrapServer)
+			}
+			if len(adminClient.configs) != c.expectedReloads {
+				t.Errorf("expected %d reloads, but got %d", c.expectedReloads, len(adminClient.configs))
+			}
+		})
+	}
+
+	password, _ := adminClient.configs[1].Get("sasl.password", "")
+	if password != "new-secret" {
+		t.Errorf("expected the admin client is recreated with the new password, but got %v", password)
+	}
+}
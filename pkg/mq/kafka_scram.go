@@ -0,0 +1,170 @@
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonv1alpha1client "open-cluster-management.io/api/client/addon/clientset/versioned/typed/addon/v1alpha1"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+)
+
+const scramPasswordLength = 32
+
+// ensureScramUser creates the SCRAM user of the given cluster. The credential of the user is stored in the customized
+// variables of an AddOnDeploymentConfig in the cluster namespace, and the config is added to the maestro-addon
+// ManagedClusterAddOn of the cluster, so the addon framework renders the credential to the agent config on the managed
+// cluster. The credential on the broker is overwritten if the config is newly created, so the credential always
+// matches the password in the config.
+func (c *KafkaAuthzCreator) ensureScramUser(ctx context.Context, clusterName string) error {
+	user := helpers.ToKafkaScramUser(clusterName)
+
+	password, created, err := ensureScramConfig(ctx, c.addOnClient, clusterName, user, c.scramMechanism.String())
+	if err != nil {
+		return err
+	}
+
+	if err := helpers.EnsureScramCredential(ctx, c.adminClient, c.scramMechanism, user, password, created); err != nil {
+		return err
+	}
+
+	return ensureScramConfigReference(ctx, c.addOnClient, clusterName)
+}
+
+// deleteScramUser deletes the SCRAM credential of the given cluster from the broker and the config from the cluster
+// namespace.
+func (c *KafkaAuthzCreator) deleteScramUser(ctx context.Context, clusterName string) error {
+	if err := helpers.DeleteScramCredential(ctx, c.adminClient, c.scramMechanism,
+		helpers.ToKafkaScramUser(clusterName)); err != nil {
+		return err
+	}
+
+	err := c.addOnClient.AddOnDeploymentConfigs(clusterName).Delete(
+		ctx, common.MessageQueueScramConfigName, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ensureScramConfig returns the password in the SCRAM config of the given cluster, if the config does not exist,
+// a new password is generated and stored in a new config.
+func ensureScramConfig(ctx context.Context, addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	clusterName, user, mechanism string) (string, bool, error) {
+	configs := addOnClient.AddOnDeploymentConfigs(clusterName)
+
+	config, err := configs.Get(ctx, common.MessageQueueScramConfigName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		password, err := generateScramPassword()
+		if err != nil {
+			return "", false, err
+		}
+
+		config = &addonv1alpha1.AddOnDeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      common.MessageQueueScramConfigName,
+				Namespace: clusterName,
+				Labels:    map[string]string{common.ClusterNameLabel: clusterName},
+			},
+			Spec: addonv1alpha1.AddOnDeploymentConfigSpec{
+				CustomizedVariables: toScramVariables(user, password, mechanism),
+			},
+		}
+		if _, err := configs.Create(ctx, config, metav1.CreateOptions{}); err != nil {
+			return "", false, err
+		}
+
+		return password, true, nil
+	case err != nil:
+		return "", false, err
+	}
+
+	variables := map[string]string{}
+	for _, variable := range config.Spec.CustomizedVariables {
+		variables[variable.Name] = variable.Value
+	}
+
+	password := variables[common.MessageQueueScramPasswordVariable]
+	if len(password) != 0 && variables[common.MessageQueueScramUserVariable] == user &&
+		variables[common.MessageQueueScramMechanismVariable] == mechanism {
+		return password, false, nil
+	}
+
+	// the config is changed unexpectedly, regenerate the password
+	password, err = generateScramPassword()
+	if err != nil {
+		return "", false, err
+	}
+
+	config = config.DeepCopy()
+	config.Spec.CustomizedVariables = toScramVariables(user, password, mechanism)
+	if _, err := configs.Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return "", false, err
+	}
+
+	return password, true, nil
+}
+
+// ensureScramConfigReference adds the SCRAM config to the configs of the maestro-addon ManagedClusterAddOn of the
+// given cluster. It does nothing if the ManagedClusterAddOn does not exist, the cluster is reconciled again when its
+// maestro-addon is created.
+func ensureScramConfigReference(ctx context.Context, addOnClient addonv1alpha1client.AddonV1alpha1Interface,
+	clusterName string) error {
+	addOns := addOnClient.ManagedClusterAddOns(clusterName)
+
+	addOn, err := addOns.Get(ctx, common.AddOnName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	required := toScramConfigReference(clusterName)
+	for _, config := range addOn.Spec.Configs {
+		if config == required {
+			return nil
+		}
+	}
+
+	addOn = addOn.DeepCopy()
+	addOn.Spec.Configs = append(addOn.Spec.Configs, required)
+	_, err = addOns.Update(ctx, addOn, metav1.UpdateOptions{})
+	return err
+}
+
+func toScramConfigReference(clusterName string) addonv1alpha1.AddOnConfig {
+	return addonv1alpha1.AddOnConfig{
+		ConfigGroupResource: addonv1alpha1.ConfigGroupResource{
+			Group:    addonv1alpha1.GroupName,
+			Resource: "addondeploymentconfigs",
+		},
+		ConfigReferent: addonv1alpha1.ConfigReferent{
+			Namespace: clusterName,
+			Name:      common.MessageQueueScramConfigName,
+		},
+	}
+}
+
+func toScramVariables(user, password, mechanism string) []addonv1alpha1.CustomizedVariable {
+	return []addonv1alpha1.CustomizedVariable{
+		{Name: common.MessageQueueScramUserVariable, Value: user},
+		{Name: common.MessageQueueScramPasswordVariable, Value: password},
+		{Name: common.MessageQueueScramMechanismVariable, Value: mechanism},
+	}
+}
+
+func generateScramPassword() (string, error) {
+	data := make([]byte, scramPasswordLength)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
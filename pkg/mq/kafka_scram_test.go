@@ -0,0 +1,158 @@
+package mq
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	fakeaddon "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+)
+
+func TestEnsureScramConfig(t *testing.T) {
+	clusterName := "cluster1"
+	user := "cluster1-maestro-addon-agent"
+
+	cases := []struct {
+		name             string
+		configs          []runtime.Object
+		expectedCreated  bool
+		expectedPassword string
+	}{
+		{
+			name:            "create a new config",
+			configs:         []runtime.Object{},
+			expectedCreated: true,
+		},
+		{
+			name: "reuse the existing config",
+			configs: []runtime.Object{&addonv1alpha1.AddOnDeploymentConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      common.MessageQueueScramConfigName,
+					Namespace: clusterName,
+				},
+				Spec: addonv1alpha1.AddOnDeploymentConfigSpec{
+					CustomizedVariables: toScramVariables(user, "password", SASLMechanismScramSHA512),
+				},
+			}},
+			expectedPassword: "password",
+		},
+		{
+			name: "regenerate the password of a changed config",
+			configs: []runtime.Object{&addonv1alpha1.AddOnDeploymentConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      common.MessageQueueScramConfigName,
+					Namespace: clusterName,
+				},
+				Spec: addonv1alpha1.AddOnDeploymentConfigSpec{
+					CustomizedVariables: []addonv1alpha1.CustomizedVariable{
+						{Name: common.MessageQueueScramUserVariable, Value: user},
+					},
+				},
+			}},
+			expectedCreated: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := fakeaddon.NewSimpleClientset(c.configs...)
+			password, created, err := ensureScramConfig(context.Background(), addOnClient.AddonV1alpha1(),
+				clusterName, user, SASLMechanismScramSHA512)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if created != c.expectedCreated {
+				t.Errorf("expected created %t, but got %t", c.expectedCreated, created)
+			}
+
+			if len(c.expectedPassword) != 0 && password != c.expectedPassword {
+				t.Errorf("expected password %s, but got %s", c.expectedPassword, password)
+			}
+
+			config, err := addOnClient.AddonV1alpha1().AddOnDeploymentConfigs(clusterName).Get(
+				context.Background(), common.MessageQueueScramConfigName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			variables := map[string]string{}
+			for _, variable := range config.Spec.CustomizedVariables {
+				variables[variable.Name] = variable.Value
+			}
+			if variables[common.MessageQueueScramPasswordVariable] != password {
+				t.Errorf("the password in the config is not matched")
+			}
+			if variables[common.MessageQueueScramUserVariable] != user {
+				t.Errorf("unexpected user %s", variables[common.MessageQueueScramUserVariable])
+			}
+		})
+	}
+}
+
+func TestEnsureScramConfigReference(t *testing.T) {
+	clusterName := "cluster1"
+
+	cases := []struct {
+		name            string
+		addOns          []runtime.Object
+		expectedActions []string
+	}{
+		{
+			name:            "the addon does not exist",
+			expectedActions: []string{"get"},
+		},
+		{
+			name: "add the config to the addon",
+			addOns: []runtime.Object{&addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{Name: common.AddOnName, Namespace: clusterName},
+			}},
+			expectedActions: []string{"get", "update"},
+		},
+		{
+			name: "the config is referenced",
+			addOns: []runtime.Object{&addonv1alpha1.ManagedClusterAddOn{
+				ObjectMeta: metav1.ObjectMeta{Name: common.AddOnName, Namespace: clusterName},
+				Spec: addonv1alpha1.ManagedClusterAddOnSpec{
+					Configs: []addonv1alpha1.AddOnConfig{toScramConfigReference(clusterName)},
+				},
+			}},
+			expectedActions: []string{"get"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addOnClient := fakeaddon.NewSimpleClientset(c.addOns...)
+			if err := ensureScramConfigReference(context.Background(), addOnClient.AddonV1alpha1(), clusterName); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actions := addOnClient.Actions()
+			if len(actions) != len(c.expectedActions) {
+				t.Fatalf("expected actions %v, but got %v", c.expectedActions, actions)
+			}
+			for i, action := range actions {
+				if action.GetVerb() != c.expectedActions[i] {
+					t.Errorf("expected actions %v, but got %v", c.expectedActions, actions)
+				}
+			}
+
+			if len(c.addOns) == 0 {
+				return
+			}
+			addOn, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns(clusterName).Get(
+				context.Background(), common.AddOnName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(addOn.Spec.Configs) != 1 || addOn.Spec.Configs[0] != toScramConfigReference(clusterName) {
+				t.Errorf("unexpected configs %v", addOn.Spec.Configs)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package mq
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+)
+
+const MessageQueueMQTT = "mqtt"
+
+// MQTTConfig is the config of the MQTT broker, it has the same format with the maestro MQTT config, so the
+// maestro MQTT config file can be shared with the maestro-addon. The user must be allowed to send the mosquitto
+// dynamic security commands.
+type MQTTConfig struct {
+	// BrokerHost is the host of the MQTT broker (hostname:port).
+	BrokerHost string `json:"brokerHost" yaml:"brokerHost"`
+	// Username is the username for basic authentication to connect the MQTT broker.
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	// Password is the password for basic authentication to connect the MQTT broker.
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+
+	// CAFile is the file path to a cert file for the MQTT broker certificate authority.
+	CAFile string `json:"caFile,omitempty" yaml:"caFile,omitempty"`
+	// ClientCertFile is the file path to a client cert file for TLS.
+	ClientCertFile string `json:"clientCertFile,omitempty" yaml:"clientCertFile,omitempty"`
+	// ClientKeyFile is the file path to a client key file for TLS.
+	ClientKeyFile string `json:"clientKeyFile,omitempty" yaml:"clientKeyFile,omitempty"`
+
+	// DialTimeout is the timeout when establishing a MQTT TCP connection, defaults to 60s.
+	DialTimeout *time.Duration `json:"dialTimeout,omitempty" yaml:"dialTimeout,omitempty"`
+
+	// SourceID is the source ID of the Maestro server, the agent topics are sources/<sourceID>/consumers/<cluster>,
+	// defaults to maestro.
+	SourceID string `json:"sourceID,omitempty" yaml:"sourceID,omitempty"`
+}
+
+// LoadMQTTConfig loads the MQTT config from the given file and validates it.
+func LoadMQTTConfig(configPath string) (*MQTTConfig, error) {
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &MQTTConfig{}
+	if err := yaml.Unmarshal(configData, config); err != nil {
+		return nil, err
+	}
+
+	if config.BrokerHost == "" {
+		return nil, fmt.Errorf("brokerHost is required")
+	}
+
+	if (config.ClientCertFile == "" && config.ClientKeyFile != "") ||
+		(config.ClientCertFile != "" && config.ClientKeyFile == "") {
+		return nil, fmt.Errorf("either both or none of clientCertFile and clientKeyFile must be set")
+	}
+	if config.ClientCertFile != "" && config.ClientKeyFile != "" && config.CAFile == "" {
+		return nil, fmt.Errorf("setting clientCertFile and clientKeyFile requires caFile")
+	}
+
+	if config.SourceID == "" {
+		config.SourceID = defaultSourceID
+	}
+
+	return config, nil
+}
+
+func toMQTTDialOptions(config *MQTTConfig) (helpers.MQTTDialOptions, error) {
+	options := helpers.MQTTDialOptions{
+		BrokerHost: config.BrokerHost,
+		Username:   config.Username,
+		Password:   config.Password,
+	}
+
+	if config.DialTimeout != nil {
+		options.DialTimeout = *config.DialTimeout
+	}
+
+	if config.CAFile == "" {
+		return options, nil
+	}
+
+	caData, err := os.ReadFile(config.CAFile)
+	if err != nil {
+		return options, err
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caData) {
+		return options, fmt.Errorf("failed to append the ca certificates from %s", config.CAFile)
+	}
+
+	options.TLSConfig = &tls.Config{
+		RootCAs:    certPool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.ClientCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return options, err
+		}
+		options.TLSConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return options, nil
+}
+
+// MQTTAuthzCreator grants the agent of a cluster to publish and subscribe the topics of its own cluster with the
+// mosquitto dynamic security plugin, the dynamic security client of the agent is the common name of the agent
+// certificate, so the broker must use the certificate identity as the username.
+type MQTTAuthzCreator struct {
+	client   helpers.MQTTDynSecClient
+	sourceID string
+}
+
+func (c *MQTTAuthzCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
+	return helpers.CreateMQTTACLs(ctx, c.client, c.sourceID, clusterName)
+}
+
+func (c *MQTTAuthzCreator) DeleteAuthorizations(ctx context.Context, clusterName string) error {
+	return helpers.DeleteMQTTACLs(ctx, c.client, clusterName)
+}
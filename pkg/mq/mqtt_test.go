@@ -0,0 +1,84 @@
+package mq
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadMQTTConfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		config        string
+		expectedError bool
+		validate      func(t *testing.T, config *MQTTConfig)
+	}{
+		{
+			name:          "broker host is required",
+			config:        "caFile: /ca.crt",
+			expectedError: true,
+		},
+		{
+			name: "maestro mqtt config",
+			config: `brokerHost: mosquitto:8883
+username: admin
+password: secret
+caFile: /ca.crt
+dialTimeout: 10s
+topics:
+  sourceEvents: sources/maestro/consumers/+/sourceevents
+  agentEvents: sources/maestro/consumers/+/agentevents
+`,
+			validate: func(t *testing.T, config *MQTTConfig) {
+				if config.BrokerHost != "mosquitto:8883" || config.Username != "admin" || config.Password != "secret" {
+					t.Errorf("unexpected config %v", config)
+				}
+				if config.DialTimeout == nil || *config.DialTimeout != 10*time.Second {
+					t.Errorf("unexpected dial timeout %v", config.DialTimeout)
+				}
+				if config.SourceID != "maestro" {
+					t.Errorf("unexpected source id %s", config.SourceID)
+				}
+			},
+		},
+		{
+			name: "client cert without client key",
+			config: `brokerHost: mosquitto:8883
+caFile: /ca.crt
+clientCertFile: /client.crt
+`,
+			expectedError: true,
+		},
+		{
+			name: "client cert without ca",
+			config: `brokerHost: mosquitto:8883
+clientCertFile: /client.crt
+clientKeyFile: /client.key
+`,
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(c.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadMQTTConfig(configPath)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c.validate(t, config)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// SecretConfigScheme is the scheme of the message queue broker config that is read from a Secret, e.g.
+// secret://maestro/maestro-kafka-config.
+const SecretConfigScheme = "secret://"
+
+const (
+	// the key of the broker config in the Secret, the config is same with the config file
+	secretConfigKey = "config.yaml"
+	// the keys that the Kafka config is built from if the Secret does not have the config.yaml
+	secretBootstrapServerKey = "bootstrapServer"
+	secretCAKey              = "ca.crt"
+	secretClientCertKey      = "tls.crt"
+	secretClientKeyKey       = "tls.key"
+)
+
+// IsSecretConfig returns true if the given broker config is a Secret reference.
+func IsSecretConfig(config string) bool {
+	return strings.HasPrefix(config, SecretConfigScheme)
+}
+
+// ParseSecretConfig returns the namespace and name of the Secret from the secret://<namespace>/<name> reference.
+func ParseSecretConfig(config string) (string, string, error) {
+	namespace, name, found := strings.Cut(strings.TrimPrefix(config, SecretConfigScheme), "/")
+	if !IsSecretConfig(config) || !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("the secret config %q must be in the format %s<namespace>/<name>", config, SecretConfigScheme)
+	}
+	return namespace, name, nil
+}
+
+// WatchSecretConfig watches the Secret of the given secret://<namespace>/<name> reference with an informer until the
+// context is done, the data of the Secret is written to the files in the given directory, so the broker config and the
+// TLS materials can be loaded as the files. The files are rewritten when the Secret is updated, and the config watcher
+// of the creator reloads them. The path of the config file is returned after the Secret is written for the first time.
+func WatchSecretConfig(ctx context.Context, kubeClient kubernetes.Interface, config, dir string) (string, error) {
+	namespace, name, err := ParseSecretConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	secretInformer := informerFactory.Core().V1().Secrets()
+
+	// the files are written by the informer handlers and the first write below, they must not be written concurrently
+	var lock sync.Mutex
+	writeLocked := func(secret *corev1.Secret) error {
+		lock.Lock()
+		defer lock.Unlock()
+		return writeSecretConfig(dir, secret)
+	}
+
+	write := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Name != name {
+			return
+		}
+
+		if err := writeLocked(secret); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to write the broker config of the secret", "secret", config)
+			return
+		}
+		klog.FromContext(ctx).V(2).Info(fmt.Sprintf("the broker config of the secret %s is written to %s", config, dir))
+	}
+	if _, err := secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    write,
+		UpdateFunc: func(_, newObj interface{}) { write(newObj) },
+	}); err != nil {
+		return "", err
+	}
+
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), secretInformer.Informer().HasSynced) {
+		return "", fmt.Errorf("failed to sync the informer of the secret %s", config)
+	}
+
+	secret, err := secretInformer.Lister().Secrets(namespace).Get(name)
+	if err != nil {
+		return "", err
+	}
+
+	// the secret is written here as well, so the config file exists when the path is returned
+	if err := writeLocked(secret); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, secretConfigKey), nil
+}
+
+// writeSecretConfig writes each key of the Secret to a file in the given directory, the files of the removed keys are
+// deleted. If the Secret does not have the config.yaml, the Kafka config is built from the bootstrapServer, ca.crt,
+// tls.crt and tls.key of the Secret. The relative file paths in the config are relative to the directory.
+func writeSecretConfig(dir string, secret *corev1.Secret) error {
+	data := map[string][]byte{}
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+
+	if _, ok := data[secretConfigKey]; !ok {
+		config, err := toSecretKafkaConfig(secret)
+		if err != nil {
+			return err
+		}
+		data[secretConfigKey] = config
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// the config is written at last, so the files that it references are updated before it
+	keys := sets.List(sets.KeySet(data).Delete(secretConfigKey))
+	for _, key := range append(keys, secretConfigKey) {
+		if err := writeFileAtomically(filepath.Join(dir, key), data[key]); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, ok := data[entry.Name()]; ok || entry.IsDir() {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toSecretKafkaConfig(secret *corev1.Secret) ([]byte, error) {
+	bootstrapServer := strings.TrimSpace(string(secret.Data[secretBootstrapServerKey]))
+	if bootstrapServer == "" {
+		return nil, fmt.Errorf("the secret %s/%s requires either %s or %s",
+			secret.Namespace, secret.Name, secretConfigKey, secretBootstrapServerKey)
+	}
+
+	config := &KafkaConfig{BootstrapServer: bootstrapServer}
+	if _, ok := secret.Data[secretCAKey]; ok {
+		config.CAFile = secretCAKey
+	}
+	if _, ok := secret.Data[secretClientCertKey]; ok {
+		config.ClientCertFile = secretClientCertKey
+	}
+	if _, ok := secret.Data[secretClientKeyKey]; ok {
+		config.ClientKeyFile = secretClientKeyKey
+	}
+
+	return yaml.Marshal(config)
+}
+
+// writeFileAtomically writes the data to a temporary file and renames it, so the readers do not get a partial file.
+func writeFileAtomically(path string, data []byte) error {
+	existing, err := os.ReadFile(path)
+	if err == nil && string(existing) == string(data) {
+		return nil
+	}
+
+	tmpFile := fmt.Sprintf("%s.tmp", path)
+	if err := os.WriteFile(tmpFile, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
@@ -0,0 +1,146 @@
+package mq
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseSecretConfig(t *testing.T) {
+	cases := []struct {
+		name              string
+		config            string
+		expectedNamespace string
+		expectedName      string
+		expectedError     bool
+	}{
+		{
+			name:              "secret config",
+			config:            "secret://maestro/maestro-kafka-config",
+			expectedNamespace: "maestro",
+			expectedName:      "maestro-kafka-config",
+		},
+		{
+			name:          "config file",
+			config:        "/configs/kafka/config.yaml",
+			expectedError: true,
+		},
+		{
+			name:          "without namespace",
+			config:        "secret://maestro-kafka-config",
+			expectedError: true,
+		},
+		{
+			name:          "invalid name",
+			config:        "secret://maestro/kafka/config",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			namespace, name, err := ParseSecretConfig(c.config)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if namespace != c.expectedNamespace || name != c.expectedName {
+				t.Errorf("unexpected namespace %s and name %s", namespace, name)
+			}
+		})
+	}
+}
+
+func TestWatchSecretConfig(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "maestro-kafka-config", Namespace: "maestro"},
+		Data: map[string][]byte{
+			"bootstrapServer": []byte("kafka:9092"),
+			"ca.crt":          []byte("ca"),
+			"tls.crt":         []byte("cert"),
+			"tls.key":         []byte("key"),
+		},
+	}
+	kubeClient := fakekube.NewSimpleClientset(secret)
+
+	dir := t.TempDir()
+	configPath, err := WatchSecretConfig(ctx, kubeClient, "secret://maestro/maestro-kafka-config", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadKafkaConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BootstrapServer != "kafka:9092" || config.CAFile != filepath.Join(dir, "ca.crt") ||
+		config.ClientCertFile != filepath.Join(dir, "tls.crt") || config.ClientKeyFile != filepath.Join(dir, "tls.key") {
+		t.Errorf("unexpected config %v", config)
+	}
+
+	// the secret is updated to use the SASL
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{
+		"config.yaml": []byte("bootstrapServer: kafka-new:9092\ncaFile: ca.crt\n" +
+			"sasl:\n  mechanism: PLAIN\n  usernameFile: username\n  passwordFile: password\n"),
+		"ca.crt":   []byte("new-ca"),
+		"username": []byte("admin"),
+		"password": []byte("secret"),
+	}
+	if _, err := kubeClient.CoreV1().Secrets("maestro").Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, 10*time.Second, true,
+		func(ctx context.Context) (bool, error) {
+			config, err := LoadKafkaConfig(configPath)
+			if err != nil {
+				return false, nil
+			}
+			return config.BootstrapServer == "kafka-new:9092", nil
+		}); err != nil {
+		t.Fatalf("the config is not updated: %v", err)
+	}
+
+	configMap, err := ToKafkaConfigMap(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, _ := configMap.Get("sasl.password", ""); password != "secret" {
+		t.Errorf("unexpected sasl password %v", password)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "tls.key")); !os.IsNotExist(err) {
+		t.Errorf("expected the file of the removed key is deleted, but got %v", err)
+	}
+}
+
+func TestWatchSecretConfigWithoutBootstrapServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fakekube.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "maestro-kafka-config", Namespace: "maestro"},
+		Data:       map[string][]byte{"ca.crt": []byte("ca")},
+	})
+
+	if _, err := WatchSecretConfig(ctx, kubeClient, "secret://maestro/maestro-kafka-config", t.TempDir()); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
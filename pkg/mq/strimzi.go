@@ -0,0 +1,353 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/stolostron/maestro-addon/pkg/common"
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+)
+
+// MessageQueueStrimzi manages the ACLs of the agents with the Strimzi KafkaUser resources, the Strimzi User Operator
+// applies the ACLs to the Kafka broker, so the maestro-addon manager does not need the Kafka super user credentials.
+const MessageQueueStrimzi = "strimzi"
+
+const (
+	strimziClusterLabel = "strimzi.io/cluster"
+
+	// strimziPrincipalPrefix is the prefix of the principals that Strimzi binds the ACLs of a tls-external KafkaUser
+	// to, the principal is User:CN=<KafkaUser name>.
+	strimziPrincipalPrefix = "User:CN="
+	// defaultStrimziPrincipalTemplate renders the principal User:CN=<cluster>-maestro-addon-agent.
+	defaultStrimziPrincipalTemplate = "User:CN={{ .ClusterName }}-{{ .AddOnName }}-agent"
+)
+
+var kafkaUserGVR = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkausers"}
+
+type StrimziConfig struct {
+	// Namespace is the namespace of the Strimzi Kafka cluster, the KafkaUser resources are created in it.
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// KafkaCluster is the name of the Strimzi Kafka cluster.
+	KafkaCluster string `json:"kafkaCluster" yaml:"kafkaCluster"`
+
+	// ACLProfile is the profile of the ACLs that are granted to the agents, it can be restricted or permissive,
+	// defaults to restricted.
+	ACLProfile string `json:"aclProfile,omitempty" yaml:"aclProfile,omitempty"`
+
+	// TopicLayout is the layout of the event topics, only the shared layout is supported. The topics must be created
+	// in advance.
+	TopicLayout string `json:"topicLayout,omitempty" yaml:"topicLayout,omitempty"`
+
+	// PrincipalTemplate is a Go template of the principal that the Kafka listener maps the agent certificate to,
+	// defaults to User:CN={{ .ClusterName }}-{{ .AddOnName }}-agent. It must be rendered to User:CN=<name>, the
+	// KafkaUser of the agent is named <name>, because Strimzi binds the ACLs of the KafkaUser to this principal.
+	PrincipalTemplate string `json:"principalTemplate,omitempty" yaml:"principalTemplate,omitempty"`
+
+	// ClientQuotas are the default client quotas of the agents, they are set to the quotas of the KafkaUser resources.
+	ClientQuotas helpers.KafkaClientQuotas `json:"clientQuotas,omitempty" yaml:"clientQuotas,omitempty"`
+}
+
+// LoadStrimziConfig loads the Strimzi config from the given file and validates it. The unknown fields are rejected,
+// so the options of the kafka message queue that Strimzi does not support (e.g. agentAuthentication and topicPrefix)
+// are not ignored silently.
+func LoadStrimziConfig(configPath string) (*StrimziConfig, error) {
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &StrimziConfig{}
+	if err := yaml.UnmarshalStrict(configData, config); err != nil {
+		return nil, fmt.Errorf("invalid strimzi config: %v", err)
+	}
+
+	if config.Namespace == "" {
+		return nil, fmt.Errorf("namespace is required")
+	}
+	if config.KafkaCluster == "" {
+		return nil, fmt.Errorf("kafkaCluster is required")
+	}
+
+	switch helpers.KafkaACLProfile(config.ACLProfile) {
+	case "":
+		config.ACLProfile = string(helpers.KafkaACLProfileRestricted)
+	case helpers.KafkaACLProfileRestricted, helpers.KafkaACLProfilePermissive:
+	default:
+		return nil, fmt.Errorf("unsupported aclProfile %q, it must be %s or %s", config.ACLProfile,
+			helpers.KafkaACLProfileRestricted, helpers.KafkaACLProfilePermissive)
+	}
+
+	if err := validateKafkaTopicLayout(config.TopicLayout); err != nil {
+		return nil, err
+	}
+	config.TopicLayout = kafkaTopicLayoutShared
+
+	if config.PrincipalTemplate == "" {
+		config.PrincipalTemplate = defaultStrimziPrincipalTemplate
+	}
+	// render the template with a sample cluster to find the errors earlier
+	principalTemplate, err := helpers.NewKafkaPrincipalTemplate("", config.PrincipalTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toKafkaUserName(principalTemplate, "cluster1"); err != nil {
+		return nil, err
+	}
+
+	if err := config.ClientQuotas.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid clientQuotas: %v", err)
+	}
+
+	return config, nil
+}
+
+// newStrimziAuthzCreator returns a StrimziAuthzCreator with the given Strimzi config that is loaded and validated.
+func newStrimziAuthzCreator(dynamicClient dynamic.Interface, config *StrimziConfig) (*StrimziAuthzCreator, error) {
+	principalTemplate, err := helpers.NewKafkaPrincipalTemplate("", config.PrincipalTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StrimziAuthzCreator{
+		dynamicClient: dynamicClient,
+		namespace:     config.Namespace,
+		kafkaCluster:  config.KafkaCluster,
+		aclOptions: helpers.KafkaACLOptions{
+			Profile: helpers.KafkaACLProfile(config.ACLProfile),
+			// the agents authenticate with the certificates that are signed by the maestro-addon custom signer
+			AgentAuthentication: helpers.KafkaAgentAuthenticationCertificate,
+			PrincipalTemplate:   principalTemplate,
+		},
+		clientQuotas: config.ClientQuotas,
+	}, nil
+}
+
+// StrimziAuthzCreator creates a KafkaUser for each cluster with the ACLs of the agent. The Strimzi binds the ACLs
+// to the principal User:CN=<KafkaUser name>, so the KafkaUser is named after the principal that the Kafka listener
+// maps the agent certificate to.
+type StrimziAuthzCreator struct {
+	dynamicClient dynamic.Interface
+	namespace     string
+	kafkaCluster  string
+	aclOptions    helpers.KafkaACLOptions
+	clientQuotas  helpers.KafkaClientQuotas
+}
+
+func (c *StrimziAuthzCreator) CreateAuthorizations(ctx context.Context, clusterName string) error {
+	return c.CreateAuthorizationsWithQuotas(ctx, clusterName, helpers.KafkaClientQuotas{})
+}
+
+// CreateAuthorizationsWithQuotas creates or updates the KafkaUser of the cluster, the quotas of the KafkaUser are the
+// default quotas that are overridden by the given overrides.
+func (c *StrimziAuthzCreator) CreateAuthorizationsWithQuotas(ctx context.Context,
+	clusterName string, overrides helpers.KafkaClientQuotas) error {
+	required, err := c.toKafkaUser(clusterName, c.clientQuotas.Merge(overrides))
+	if err != nil {
+		return err
+	}
+
+	kafkaUsers := c.dynamicClient.Resource(kafkaUserGVR).Namespace(c.namespace)
+	existing, err := kafkaUsers.Get(ctx, required.GetName(), metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = kafkaUsers.Create(ctx, required, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(existing.Object["spec"], required.Object["spec"]) &&
+		equality.Semantic.DeepEqual(existing.GetLabels(), required.GetLabels()) {
+		return nil
+	}
+
+	existing = existing.DeepCopy()
+	existing.SetLabels(required.GetLabels())
+	existing.Object["spec"] = required.Object["spec"]
+	_, err = kafkaUsers.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *StrimziAuthzCreator) DeleteAuthorizations(ctx context.Context, clusterName string) error {
+	name, err := toKafkaUserName(c.aclOptions.PrincipalTemplate, clusterName)
+	if err != nil {
+		return err
+	}
+
+	err = c.dynamicClient.Resource(kafkaUserGVR).Namespace(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *StrimziAuthzCreator) toKafkaUser(clusterName string,
+	quotas helpers.KafkaClientQuotas) (*unstructured.Unstructured, error) {
+	name, err := toKafkaUserName(c.aclOptions.PrincipalTemplate, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	aclBindings, err := helpers.KafkaACLBindings(c.aclOptions, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	acls, err := toStrimziACLs(aclBindings)
+	if err != nil {
+		return nil, err
+	}
+
+	kafkaUser := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": kafkaUserGVR.GroupVersion().String(),
+		"kind":       "KafkaUser",
+		"spec": map[string]interface{}{
+			// the agent certificates are signed by the maestro-addon custom signer
+			"authentication": map[string]interface{}{"type": "tls-external"},
+			"authorization": map[string]interface{}{
+				"type": "simple",
+				"acls": acls,
+			},
+		},
+	}}
+	if !quotas.IsEmpty() {
+		kafkaUser.Object["spec"].(map[string]interface{})["quotas"] = toStrimziQuotas(quotas)
+	}
+	kafkaUser.SetName(name)
+	kafkaUser.SetNamespace(c.namespace)
+	kafkaUser.SetLabels(map[string]string{
+		strimziClusterLabel:     c.kafkaCluster,
+		common.ClusterNameLabel: clusterName,
+	})
+	return kafkaUser, nil
+}
+
+// toStrimziQuotas converts the client quotas to the Strimzi KafkaUser quotas.
+func toStrimziQuotas(quotas helpers.KafkaClientQuotas) map[string]interface{} {
+	strimziQuotas := map[string]interface{}{}
+	if quotas.ProducerByteRate != nil {
+		strimziQuotas["producerByteRate"] = *quotas.ProducerByteRate
+	}
+	if quotas.ConsumerByteRate != nil {
+		strimziQuotas["consumerByteRate"] = *quotas.ConsumerByteRate
+	}
+	if quotas.RequestPercentage != nil {
+		strimziQuotas["requestPercentage"] = *quotas.RequestPercentage
+	}
+	return strimziQuotas
+}
+
+// toStrimziACLs converts the ACL bindings to the Strimzi ACL rules, the operations on a same resource are merged
+// into one rule.
+func toStrimziACLs(aclBindings []kafka.ACLBinding) ([]interface{}, error) {
+	acls := []interface{}{}
+	indexes := map[string]int{}
+	for _, binding := range aclBindings {
+		resourceType, err := toStrimziResourceType(binding.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		patternType, err := toStrimziPatternType(binding.ResourcePatternType)
+		if err != nil {
+			return nil, err
+		}
+
+		operation, err := toStrimziOperation(binding.Operation)
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("%s/%s/%s", resourceType, patternType, binding.Name)
+		if index, ok := indexes[key]; ok {
+			acl := acls[index].(map[string]interface{})
+			acl["operations"] = append(acl["operations"].([]interface{}), operation)
+			continue
+		}
+
+		indexes[key] = len(acls)
+		acls = append(acls, map[string]interface{}{
+			"resource": map[string]interface{}{
+				"type":        resourceType,
+				"name":        binding.Name,
+				"patternType": patternType,
+			},
+			"operations": []interface{}{operation},
+			"host":       binding.Host,
+			"type":       "allow",
+		})
+	}
+
+	return acls, nil
+}
+
+func toStrimziResourceType(resourceType kafka.ResourceType) (string, error) {
+	switch resourceType {
+	case kafka.ResourceTopic:
+		return "topic", nil
+	case kafka.ResourceGroup:
+		return "group", nil
+	default:
+		return "", fmt.Errorf("unsupported acl resource type %s", resourceType)
+	}
+}
+
+func toStrimziPatternType(patternType kafka.ResourcePatternType) (string, error) {
+	switch patternType {
+	case kafka.ResourcePatternTypeLiteral:
+		return "literal", nil
+	case kafka.ResourcePatternTypePrefixed:
+		return "prefix", nil
+	default:
+		return "", fmt.Errorf("unsupported acl resource pattern type %s", patternType)
+	}
+}
+
+func toStrimziOperation(operation kafka.ACLOperation) (string, error) {
+	switch operation {
+	case kafka.ACLOperationAll:
+		return "All", nil
+	case kafka.ACLOperationRead:
+		return "Read", nil
+	case kafka.ACLOperationWrite:
+		return "Write", nil
+	case kafka.ACLOperationDescribe:
+		return "Describe", nil
+	default:
+		return "", fmt.Errorf("unsupported acl operation %s", operation)
+	}
+}
+
+// toKafkaUserName renders the principal of the agent of the given cluster and returns the KafkaUser name in it, the
+// principal must be User:CN=<name> and the name must be a valid Kubernetes resource name.
+func toKafkaUserName(principalTemplate *template.Template, clusterName string) (string, error) {
+	principal, err := helpers.ToKafkaPrincipal(principalTemplate, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := strings.CutPrefix(principal, strimziPrincipalPrefix)
+	if !ok {
+		return "", fmt.Errorf("the principal %q must be in the format %s<name>, Strimzi binds the ACLs of the "+
+			"KafkaUser <name> to this principal", principal, strimziPrincipalPrefix)
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return "", fmt.Errorf("the KafkaUser name %q of the principal %q is invalid: %s",
+			name, principal, strings.Join(errs, ", "))
+	}
+
+	return name, nil
+}
@@ -0,0 +1,294 @@
+package mq
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+
+	"github.com/stolostron/maestro-addon/pkg/helpers"
+)
+
+func newFakeStrimziAuthzCreator(t *testing.T, profile helpers.KafkaACLProfile, principalTemplate string,
+	objects ...runtime.Object) *StrimziAuthzCreator {
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{kafkaUserGVR: "KafkaUserList"}, objects...)
+	if principalTemplate == "" {
+		principalTemplate = defaultStrimziPrincipalTemplate
+	}
+
+	creator, err := newStrimziAuthzCreator(dynamicClient, &StrimziConfig{
+		Namespace:         "amq-streams",
+		KafkaCluster:      "kafka",
+		ACLProfile:        string(profile),
+		PrincipalTemplate: principalTemplate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creator
+}
+
+func TestStrimziCreateAuthorizations(t *testing.T) {
+	cases := []struct {
+		name              string
+		profile           helpers.KafkaACLProfile
+		principalTemplate string
+		existing          bool
+		expectedName      string
+		expectedACLs      []interface{}
+	}{
+		{
+			name:         "restricted acls",
+			profile:      helpers.KafkaACLProfileRestricted,
+			expectedName: "cluster1-maestro-addon-agent",
+			expectedACLs: []interface{}{
+				newStrimziACL("topic", "sourceevents", "literal", "Read", "Describe"),
+				newStrimziACL("topic", "agentevents", "literal", "Write", "Describe"),
+				newStrimziACL("group", "cluster1-work-agent", "literal", "Read", "Describe"),
+			},
+		},
+		{
+			name:         "correct the existing kafka user",
+			profile:      helpers.KafkaACLProfilePermissive,
+			existing:     true,
+			expectedName: "cluster1-maestro-addon-agent",
+			expectedACLs: []interface{}{
+				newStrimziACL("group", "*", "literal", "All"),
+				newStrimziACL("topic", "sourceevents", "literal", "All"),
+				newStrimziACL("topic", "agentevents", "literal", "All"),
+			},
+		},
+		{
+			name:              "kafka user of the mapped principal",
+			profile:           helpers.KafkaACLProfileRestricted,
+			principalTemplate: "User:CN=maestro-{{ .ClusterName }}",
+			expectedName:      "maestro-cluster1",
+			expectedACLs: []interface{}{
+				newStrimziACL("topic", "sourceevents", "literal", "Read", "Describe"),
+				newStrimziACL("topic", "agentevents", "literal", "Write", "Describe"),
+				newStrimziACL("group", "cluster1-work-agent", "literal", "Read", "Describe"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if c.existing {
+				kafkaUser, err := newFakeStrimziAuthzCreator(t, helpers.KafkaACLProfileRestricted, c.principalTemplate).
+					toKafkaUser("cluster1", helpers.KafkaClientQuotas{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				objects = append(objects, kafkaUser)
+			}
+
+			creator := newFakeStrimziAuthzCreator(t, c.profile, c.principalTemplate, objects...)
+			if err := creator.CreateAuthorizations(context.Background(), "cluster1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			kafkaUser, err := creator.dynamicClient.Resource(kafkaUserGVR).Namespace("amq-streams").Get(
+				context.Background(), c.expectedName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if kafkaUser.GetLabels()["strimzi.io/cluster"] != "kafka" {
+				t.Errorf("unexpected labels %v", kafkaUser.GetLabels())
+			}
+
+			acls, _, err := unstructured.NestedSlice(kafkaUser.Object, "spec", "authorization", "acls")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(acls, c.expectedACLs) {
+				t.Errorf("expected acls %v, but got %v", c.expectedACLs, acls)
+			}
+		})
+	}
+}
+
+func TestStrimziCreateAuthorizationsWithQuotas(t *testing.T) {
+	producerByteRate, consumerByteRate, requestPercentage := int64(1048576), int64(2097152), int64(50)
+
+	cases := []struct {
+		name           string
+		defaults       helpers.KafkaClientQuotas
+		overrides      helpers.KafkaClientQuotas
+		expectedQuotas map[string]interface{}
+	}{
+		{
+			name: "no quotas",
+		},
+		{
+			name:           "default quotas",
+			defaults:       helpers.KafkaClientQuotas{ProducerByteRate: &producerByteRate},
+			expectedQuotas: map[string]interface{}{"producerByteRate": producerByteRate},
+		},
+		{
+			name:      "override the default quotas",
+			defaults:  helpers.KafkaClientQuotas{ProducerByteRate: &producerByteRate, ConsumerByteRate: &producerByteRate},
+			overrides: helpers.KafkaClientQuotas{ConsumerByteRate: &consumerByteRate, RequestPercentage: &requestPercentage},
+			expectedQuotas: map[string]interface{}{
+				"producerByteRate":  producerByteRate,
+				"consumerByteRate":  consumerByteRate,
+				"requestPercentage": requestPercentage,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			creator := newFakeStrimziAuthzCreator(t, helpers.KafkaACLProfileRestricted, "")
+			creator.clientQuotas = c.defaults
+			if err := creator.CreateAuthorizationsWithQuotas(context.Background(), "cluster1", c.overrides); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			kafkaUser, err := creator.dynamicClient.Resource(kafkaUserGVR).Namespace("amq-streams").Get(
+				context.Background(), "cluster1-maestro-addon-agent", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			quotas, _, err := unstructured.NestedMap(kafkaUser.Object, "spec", "quotas")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(quotas, c.expectedQuotas) {
+				t.Errorf("expected quotas %v, but got %v", c.expectedQuotas, quotas)
+			}
+		})
+	}
+}
+
+func TestStrimziDeleteAuthorizations(t *testing.T) {
+	creator := newFakeStrimziAuthzCreator(t, helpers.KafkaACLProfileRestricted, "")
+	for _, cluster := range []string{"cluster1", "cluster2"} {
+		if err := creator.CreateAuthorizations(context.Background(), cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second deletion ensures the absent kafka user is ignored
+	for i := 0; i < 2; i++ {
+		if err := creator.DeleteAuthorizations(context.Background(), "cluster1"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	kafkaUsers, err := creator.dynamicClient.Resource(kafkaUserGVR).Namespace("amq-streams").List(
+		context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kafkaUsers.Items) != 1 || kafkaUsers.Items[0].GetName() != "cluster2-maestro-addon-agent" {
+		t.Errorf("expected only the kafka user of cluster2 is kept, but got %v", kafkaUsers.Items)
+	}
+}
+
+func newStrimziACL(resourceType, name, patternType string, operations ...string) interface{} {
+	ops := []interface{}{}
+	for _, operation := range operations {
+		ops = append(ops, operation)
+	}
+
+	return map[string]interface{}{
+		"resource": map[string]interface{}{
+			"type":        resourceType,
+			"name":        name,
+			"patternType": patternType,
+		},
+		"operations": ops,
+		"host":       "*",
+		"type":       "allow",
+	}
+}
+
+func TestLoadStrimziConfig(t *testing.T) {
+	cases := []struct {
+		name            string
+		config          string
+		expectedError   bool
+		expectedProfile string
+	}{
+		{
+			name:          "namespace is required",
+			config:        "kafkaCluster: kafka",
+			expectedError: true,
+		},
+		{
+			name:          "kafka cluster is required",
+			config:        "namespace: amq-streams",
+			expectedError: true,
+		},
+		{
+			name:            "default acl profile",
+			config:          "namespace: amq-streams\nkafkaCluster: kafka",
+			expectedProfile: "restricted",
+		},
+		{
+			name:          "unsupported acl profile",
+			config:        "namespace: amq-streams\nkafkaCluster: kafka\naclProfile: all",
+			expectedError: true,
+		},
+		{
+			name:          "unsupported topic layout",
+			config:        "namespace: amq-streams\nkafkaCluster: kafka\ntopicLayout: all",
+			expectedError: true,
+		},
+		{
+			name:          "per-cluster topic layout is not supported",
+			config:        "namespace: amq-streams\nkafkaCluster: kafka\ntopicLayout: perCluster",
+			expectedError: true,
+		},
+		{
+			name:          "unsupported kafka option",
+			config:        "namespace: amq-streams\nkafkaCluster: kafka\ntopicPrefix: hub1.",
+			expectedError: true,
+		},
+		{
+			name:          "principal is not bound by strimzi",
+			config:        "namespace: amq-streams\nkafkaCluster: kafka\nprincipalTemplate: User:{{ .CommonName }}",
+			expectedError: true,
+		},
+		{
+			name:          "principal is not a kafka user name",
+			config:        "namespace: amq-streams\nkafkaCluster: kafka\nprincipalTemplate: User:CN={{ .CommonName }}",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(c.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadStrimziConfig(configPath)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if config.ACLProfile != c.expectedProfile {
+				t.Errorf("expected acl profile %s, but got %s", c.expectedProfile, config.ACLProfile)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/pem"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"os"
@@ -18,6 +19,7 @@ import (
 	confluentkafka "github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"gopkg.in/yaml.v2"
 
+	"github.com/stolostron/maestro-addon/pkg/helpers"
 	"github.com/stolostron/maestro-addon/pkg/mq"
 	"github.com/stolostron/maestro-addon/test/performance/pkg/common"
 	"github.com/stolostron/maestro-addon/test/performance/pkg/util"
@@ -33,6 +35,8 @@ var (
 	kafkaServer       = flag.String("kafka-server", "", "")
 	clusterBeginIndex = flag.Int("cluster-begin-index", 1, "Begin index of the clusters")
 	clusterCounts     = flag.Int("cluster-counts", common.DEFAULT_CLUSTER_COUNTS, "Counts of the clusters")
+	reuseAdminClient  = flag.Bool("reuse-admin-client", true,
+		"Reuse one Kafka admin client for all clusters, set it to false to create an admin client for each cluster")
 )
 
 func main() {
@@ -40,19 +44,52 @@ func main() {
 
 	// init topics
 	brokerConfigPath := filepath.Join(*workDir, "config", "kafka.admin.config")
-	mqAuthzCreator, err := mq.NewMessageQueueAuthzCreator(mq.MessageQueueKafka, brokerConfigPath)
+	mqAuthzCreator, err := mq.NewMessageQueueAuthzCreator(mq.MessageQueueKafka, brokerConfigPath, nil, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if closer, ok := mqAuthzCreator.(io.Closer); ok {
+		defer closer.Close()
+	}
+	// the shared topics are created by the bootstrap, the authorizations are not created until it succeeds
+	if bootstrapper, ok := mqAuthzCreator.(mq.Bootstrapper); ok {
+		bootstrapper.Bootstrap(context.Background())
+	}
+
+	createAuthorizations := mqAuthzCreator.CreateAuthorizations
+	if !*reuseAdminClient {
+		// create the acls with a new admin client for each cluster, this is the previous behavior of the creator, it
+		// is used to compare the onboarding throughput
+		configMap, err := mq.ToKafkaConfigMap(brokerConfigPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		aclOptions, err := mq.ToKafkaACLOptions(brokerConfigPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		createAuthorizations = func(ctx context.Context, clusterName string) error {
+			adminClient, err := helpers.NewKafkaAdminClient(configMap)
+			if err != nil {
+				return err
+			}
+			defer adminClient.Close()
+
+			return helpers.CreateACLs(ctx, adminClient, aclOptions, clusterName)
+		}
+	}
 
+	aclStartTime := time.Now()
+	aclUsedTime := time.Duration(0)
 	index := *clusterBeginIndex
 	for i := 0; i < *clusterCounts; i++ {
 		clusterName := util.ClusterName(index)
 
 		startTime := time.Now()
-		if err := mqAuthzCreator.CreateAuthorizations(context.Background(), clusterName); err != nil {
+		if err := createAuthorizations(context.Background(), clusterName); err != nil {
 			log.Fatal(err)
 		}
+		aclUsedTime += time.Since(startTime)
 		fmt.Printf("the kafka acls is prepared for cluster %s, time=%dms\n",
 			clusterName, util.UsedTime(startTime, time.Millisecond))
 
@@ -61,6 +98,10 @@ func main() {
 		}
 		index = index + 1
 	}
+
+	fmt.Printf("the kafka acls is prepared for %d clusters (reuse-admin-client=%v), acl time=%dms, total time=%dms, "+
+		"throughput=%.2f clusters/s\n", *clusterCounts, *reuseAdminClient, aclUsedTime.Milliseconds(),
+		util.UsedTime(aclStartTime, time.Millisecond), float64(*clusterCounts)/aclUsedTime.Seconds())
 }
 
 func prepareKafkaAgentConfig(clusterName string) error {